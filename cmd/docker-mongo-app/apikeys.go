@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ===========================================
+// CHAVES DE API (SERVIÇO A SERVIÇO)
+// ===========================================
+
+// As chaves são enviadas no header X-API-Key e têm o formato
+// "gma_<43 caracteres base64url>". Apenas o hash SHA-256 é armazenado; o
+// valor completo é mostrado uma única vez, na criação. Cada chave recebe
+// escopos (as mesmas permissões usadas pelos papéis) e expiração opcional.
+
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyPrefix = "gma_"
+
+	// lastUsedInterval limita a frequência de escrita de last_used_at
+	lastUsedInterval = time.Minute
+)
+
+// ErrAPIKeyNotFound indica chave inexistente
+var ErrAPIKeyNotFound = errors.New("chave de API não encontrada")
+
+// APIKey é uma chave de API registrada
+type APIKey struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`
+	KeyHash    string             `json:"-" bson:"key_hash"`
+	Scopes     []Permission       `json:"scopes" bson:"scopes"`
+	CreatedBy  string             `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// active indica se a chave pode ser usada no instante informado
+func (k APIKey) active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyRepository abstrai o armazenamento das chaves de API
+type APIKeyRepository interface {
+	// Create insere a chave e preenche key.ID
+	Create(ctx context.Context, key *APIKey) error
+
+	// List retorna todas as chaves, das mais novas para as mais antigas
+	List(ctx context.Context) ([]APIKey, error)
+
+	// FindByHash retorna ErrAPIKeyNotFound se nenhuma chave tiver o hash
+	FindByHash(ctx context.Context, keyHash string) (APIKey, error)
+
+	// Revoke marca a chave como revogada; ErrAPIKeyNotFound se não existir
+	Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (APIKey, error)
+
+	// TouchLastUsed atualiza last_used_at
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// hashAPIKey calcula o hash armazenado para a chave enviada pelo cliente
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKeySecret gera o valor da chave entregue ao cliente
+func newAPIKeySecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+}
+
+// MongoAPIKeyRepository guarda as chaves na coleção "api_keys"
+type MongoAPIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAPIKeyRepository cria o repositório sobre a coleção api_keys
+func NewMongoAPIKeyRepository(db *mongo.Database) *MongoAPIKeyRepository {
+	return &MongoAPIKeyRepository{collection: db.Collection("api_keys")}
+}
+
+// Create insere uma nova chave
+func (m *MongoAPIKeyRepository) Create(ctx context.Context, key *APIKey) error {
+	result, err := m.collection.InsertOne(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List retorna todas as chaves, das mais novas para as mais antigas
+func (m *MongoAPIKeyRepository) List(ctx context.Context) ([]APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := m.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindByHash busca a chave pelo hash
+func (m *MongoAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	var key APIKey
+	err := m.collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return key, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// Revoke marca a chave como revogada (mantém o registro para auditoria)
+func (m *MongoAPIKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (APIKey, error) {
+	var key APIKey
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.M{"_id": id}
+	update := bson.A{bson.M{"$set": bson.M{"revoked_at": bson.M{"$ifNull": bson.A{"$revoked_at", at}}}}}
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return key, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// TouchLastUsed atualiza last_used_at
+func (m *MongoAPIKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := m.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
+
+// MemoryAPIKeyRepository guarda as chaves em memória (USER_STORE=memory)
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]APIKey
+}
+
+// NewMemoryAPIKeyRepository cria um repositório vazio
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{keys: make(map[primitive.ObjectID]APIKey)}
+}
+
+// Create insere uma nova chave
+func (m *MemoryAPIKeyRepository) Create(ctx context.Context, key *APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key.ID = primitive.NewObjectID()
+	m.keys[key.ID] = *key
+	return nil
+}
+
+// List retorna todas as chaves, das mais novas para as mais antigas
+func (m *MemoryAPIKeyRepository) List(ctx context.Context) ([]APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// FindByHash busca a chave pelo hash
+func (m *MemoryAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return APIKey{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrAPIKeyNotFound
+}
+
+// Revoke marca a chave como revogada
+func (m *MemoryAPIKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return APIKey{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		m.keys[id] = key
+	}
+	return key, nil
+}
+
+// TouchLastUsed atualiza last_used_at
+func (m *MemoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key, ok := m.keys[id]; ok {
+		key.LastUsedAt = &at
+		m.keys[id] = key
+	}
+	return nil
+}
+
+// ===========================================
+// AUTENTICAÇÃO POR CHAVE
+// ===========================================
+
+// authenticateAPIKey valida o header X-API-Key e retorna o chamador com as
+// permissões dos escopos da chave
+func (a *App) authenticateAPIKey(r *http.Request, secret string) (*Caller, error) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	key, err := a.APIKeys.FindByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.active(now) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		// Fora do caminho da requisição; o contexto mantém request_id nos logs
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			if err := a.APIKeys.TouchLastUsed(ctx, key.ID, now); err != nil {
+				slog.WarnContext(ctx, "erro ao atualizar last_used_at da chave de API", "error", err, "api_key_id", key.ID.Hex())
+			}
+		}()
+	}
+
+	caller := &Caller{
+		Subject:     "apikey:" + key.ID.Hex(),
+		APIKey:      true,
+		Permissions: make(map[Permission]bool, len(key.Scopes)),
+	}
+	for _, scope := range key.Scopes {
+		caller.Permissions[scope] = true
+	}
+	return caller, nil
+}
+
+// ===========================================
+// ADMINISTRAÇÃO DAS CHAVES
+// ===========================================
+
+// CreateAPIKeyHandler cria uma chave. O valor completo só aparece nesta resposta.
+// Corpo: {"name": "...", "scopes": ["users:read"], "expires_at": "RFC3339"}
+// Com autenticação, o chamador só concede escopos que ele mesmo possui.
+func (a *App) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	raw, err := decodeJSONObject(w, r)
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	key, errs := parseAPIKeyPayload(raw)
+	if len(errs) > 0 {
+		writePayloadError(w, r, errs)
+		return
+	}
+
+	caller, authenticated := CallerFromContext(r.Context())
+	if authenticated {
+		var missing []Permission
+		for _, scope := range key.Scopes {
+			if !caller.Can(scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			slog.WarnContext(r.Context(), "chave de API recusada: escopos acima das permissões do chamador",
+				"caller", caller.Subject, "scopes", missing)
+			writeErrorBody(w, r, http.StatusForbidden, map[string]interface{}{
+				"error":  "Permissão negada",
+				"reason": "a chave não pode receber escopos que o chamador não possui",
+				"scopes": missing,
+			})
+			return
+		}
+		key.CreatedBy = caller.Subject
+	}
+
+	secret := newAPIKeySecret()
+	key.KeyHash = hashAPIKey(secret)
+	key.Prefix = secret[:len(apiKeyPrefix)+6]
+	key.CreatedAt = time.Now()
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.APIKeys.Create(ctx, &key); err != nil {
+		writeRepositoryError(w, r, "Erro ao criar chave de API", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "chave de API criada", "api_key_id", key.ID.Hex(), "scopes", key.Scopes)
+
+	response := struct {
+		APIKey
+		Key string `json:"key"`
+	}{key, secret}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseAPIKeyPayload valida nome, escopos e expiração da nova chave
+func parseAPIKeyPayload(raw map[string]json.RawMessage) (APIKey, ValidationErrors) {
+	var (
+		key  APIKey
+		errs ValidationErrors
+	)
+
+	keys := make([]string, 0, len(raw))
+	for field := range raw {
+		keys = append(keys, field)
+	}
+	sort.Strings(keys)
+
+	for _, field := range keys {
+		value := raw[field]
+		switch field {
+		case "name":
+			if json.Unmarshal(value, &key.Name) != nil {
+				errs.add(field, "deve ser um texto")
+			}
+		case "scopes":
+			if json.Unmarshal(value, &key.Scopes) != nil {
+				errs.add(field, "deve ser uma lista de textos")
+			}
+		case "expires_at":
+			var expiresAt time.Time
+			if json.Unmarshal(value, &expiresAt) != nil {
+				errs.add(field, "deve ser uma data RFC 3339")
+			} else if !expiresAt.After(time.Now()) {
+				errs.add(field, "deve estar no futuro")
+			} else {
+				key.ExpiresAt = &expiresAt
+			}
+		default:
+			errs.add(field, "campo desconhecido")
+		}
+	}
+
+	key.Name = strings.TrimSpace(key.Name)
+	switch {
+	case key.Name == "" && !errs.has("name"):
+		errs.add("name", "campo obrigatório")
+	case utf8.RuneCountInString(key.Name) > maxNameLength:
+		errs.add("name", "deve ter no máximo %d caracteres", maxNameLength)
+	}
+
+	if len(key.Scopes) == 0 && !errs.has("scopes") {
+		errs.add("scopes", "informe ao menos um escopo")
+	}
+	for _, scope := range key.Scopes {
+		if !knownPermissions[scope] {
+			errs.add("scopes", "escopo desconhecido %q", scope)
+		}
+	}
+	return key, errs
+}
+
+// ListAPIKeysHandler lista as chaves (sem o valor nem o hash)
+func (a *App) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	keys, err := a.APIKeys.List(ctx)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao listar chaves de API", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"api_keys": keys,
+		"count":    len(keys),
+	})
+}
+
+// RevokeAPIKeyHandler revoga uma chave; o registro é mantido com revoked_at
+func (a *App) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "ID inválido")
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if _, err := a.APIKeys.Revoke(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			writeError(w, r, http.StatusNotFound, "Chave de API não encontrada")
+			return
+		}
+		writeRepositoryError(w, r, "Erro ao revogar chave de API", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "chave de API revogada", "api_key_id", id.Hex())
+	w.WriteHeader(http.StatusNoContent)
+}
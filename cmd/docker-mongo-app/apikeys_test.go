@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiKeyCreated é a resposta de POST /api-keys
+type apiKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// createAPIKey cria a chave via API com a credencial informada
+func createAPIKey(t *testing.T, app *App, authHeader, authValue string, scopes ...Permission) apiKeyCreated {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"name": "job-teste", "scopes": scopes})
+	w := doRequest(app, http.MethodPost, "/api-keys", string(body), authHeader, authValue)
+	expectStatus(t, w, http.StatusCreated)
+
+	var created apiKeyCreated
+	decodeBody(t, w, &created)
+	return created
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+
+	w := doRequest(app, http.MethodPost, "/api-keys", `{"name":"job","scopes":["users:read"]}`, "Authorization", admin)
+	expectStatus(t, w, http.StatusCreated)
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Cache-Control = %q", w.Header().Get("Cache-Control"))
+	}
+	var created apiKeyCreated
+	decodeBody(t, w, &created)
+	if !strings.HasPrefix(created.Key, apiKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) || created.CreatedBy == "" {
+		t.Fatalf("chave criada inesperada: %+v", created)
+	}
+
+	// A listagem não expõe o valor nem o hash
+	w = doRequest(app, http.MethodGet, "/api-keys", "", "Authorization", admin)
+	expectStatus(t, w, http.StatusOK)
+	if strings.Contains(w.Body.String(), created.Key) || strings.Contains(w.Body.String(), hashAPIKey(created.Key)) {
+		t.Fatalf("listagem expõe a chave: %s", w.Body.String())
+	}
+
+	// Autentica com os escopos da chave
+	expectStatus(t, doRequest(app, http.MethodGet, "/users", "", "X-API-Key", created.Key), http.StatusOK)
+	expectForbidden(t, doRequest(app, http.MethodPost, "/users", `{"name":"X","email":"x@example.com","age":1}`, "X-API-Key", created.Key),
+		PermUsersWrite, "a operação exige a permissão users:write, ausente nos escopos da chave de API")
+	expectStatus(t, doRequest(app, http.MethodGet, "/users", "", "X-API-Key", created.Key+"x"), http.StatusUnauthorized)
+
+	// Chave revogada deixa de autenticar
+	expectStatus(t, doRequest(app, http.MethodDelete, "/api-keys/"+created.ID.Hex(), "", "Authorization", admin), http.StatusNoContent)
+	expectStatus(t, doRequest(app, http.MethodGet, "/users", "", "X-API-Key", created.Key), http.StatusUnauthorized)
+	expectStatus(t, doRequest(app, http.MethodDelete, "/api-keys/000000000000000000000000", "", "Authorization", admin), http.StatusNotFound)
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	app, _ := newRBACTestApp(t)
+
+	// A API recusa expires_at no passado; a chave expira depois de criada
+	secret := newAPIKeySecret()
+	expired := time.Now().Add(-time.Second)
+	key := APIKey{Name: "expirada", KeyHash: hashAPIKey(secret), Scopes: []Permission{PermUsersRead}, CreatedAt: time.Now(), ExpiresAt: &expired}
+	if err := app.APIKeys.Create(context.Background(), &key); err != nil {
+		t.Fatal(err)
+	}
+
+	w := doRequest(app, http.MethodGet, "/users", "", "X-API-Key", secret)
+	expectStatus(t, w, http.StatusUnauthorized)
+	if !strings.Contains(w.Body.String(), "Chave de API inválida, expirada ou revogada") {
+		t.Fatalf("corpo: %s", w.Body.String())
+	}
+}
+
+func TestAPIKeyScopeEscalation(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+	manager := createAPIKey(t, app, "Authorization", admin, PermAPIKeys, PermUsersRead)
+
+	// Escopos que o chamador possui podem ser concedidos
+	createAPIKey(t, app, "X-API-Key", manager.Key, PermUsersRead)
+
+	// Qualquer escopo além dos do chamador é recusado
+	w := doRequest(app, http.MethodPost, "/api-keys", `{"name":"escalada","scopes":["users:read","users:manage","config:read"]}`, "X-API-Key", manager.Key)
+	expectStatus(t, w, http.StatusForbidden)
+	var denied struct {
+		Error  string       `json:"error"`
+		Scopes []Permission `json:"scopes"`
+	}
+	decodeBody(t, w, &denied)
+	if denied.Error != "Permissão negada" || len(denied.Scopes) != 2 || denied.Scopes[0] != PermUsersManage || denied.Scopes[1] != PermConfigRead {
+		t.Fatalf("403 inesperado: %+v", denied)
+	}
+
+	keys, err := app.APIKeys.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("%d chaves gravadas, esperado 2", len(keys))
+	}
+
+	// Validação do corpo vem antes da verificação de escopos
+	w = doRequest(app, http.MethodPost, "/api-keys", `{"name":"x","scopes":["root"]}`, "X-API-Key", manager.Key)
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+}
+
+func TestAPIKeyLastUsed(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+	created := createAPIKey(t, app, "Authorization", admin, PermUsersRead)
+	if created.LastUsedAt != nil {
+		t.Fatalf("chave nova com last_used_at = %v", created.LastUsedAt)
+	}
+
+	before := time.Now()
+	expectStatus(t, doRequest(app, http.MethodGet, "/users", "", "X-API-Key", created.Key), http.StatusOK)
+
+	// A atualização é assíncrona
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		key, err := app.APIKeys.FindByHash(context.Background(), hashAPIKey(created.Key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.LastUsedAt != nil {
+			if key.LastUsedAt.Before(before) {
+				t.Fatalf("last_used_at = %v, anterior ao uso", key.LastUsedAt)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("last_used_at não foi atualizado")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
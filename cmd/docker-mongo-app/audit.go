@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ===========================================
+// TRILHA DE AUDITORIA
+// ===========================================
+
+// Ações registradas na coleção audit
+const (
+	AuditUserCreate   = "user.create"
+	AuditUserUpdate   = "user.update"
+	AuditUserDelete   = "user.delete"
+	AuditUserPassword = "user.password"
+)
+
+// auditTimeout é o prazo da gravação, independente do prazo da requisição:
+// o registro precisa ser gravado mesmo que o cliente desconecte
+const auditTimeout = 5 * time.Second
+
+// FieldChange é o valor de um campo antes e depois da alteração
+type FieldChange struct {
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// AuditEntry é um registro da trilha de auditoria
+type AuditEntry struct {
+	ID        primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	Action    string                 `json:"action" bson:"action"`
+	Timestamp time.Time              `json:"timestamp" bson:"timestamp"`
+	Actor     string                 `json:"actor" bson:"actor"`
+	ActorType string                 `json:"actor_type" bson:"actor_type"`
+	Target    string                 `json:"target" bson:"target"`
+	RequestID string                 `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	IP        string                 `json:"ip" bson:"ip"`
+	Changes   map[string]FieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+}
+
+// AuditQuery são os filtros de GET /audit
+type AuditQuery struct {
+	Action string
+	Actor  string
+	Target string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditRepository abstrai o armazenamento da trilha de auditoria
+type AuditRepository interface {
+	// Insert grava um registro
+	Insert(ctx context.Context, entry *AuditEntry) error
+
+	// List retorna os registros do filtro, dos mais recentes para os mais
+	// antigos, e o total
+	List(ctx context.Context, query AuditQuery) ([]AuditEntry, int64, error)
+}
+
+// auditSnapshot são os campos auditados de User (nunca o hash da senha)
+func auditSnapshot(user User) map[string]interface{} {
+	return map[string]interface{}{
+		"name":  user.Name,
+		"email": user.Email,
+		"age":   user.Age,
+		"roles": user.Roles,
+	}
+}
+
+// diffUsers retorna os campos alterados; before ou after nil representam
+// criação e remoção
+func diffUsers(before, after *User) map[string]FieldChange {
+	var old, cur map[string]interface{}
+	if before != nil {
+		old = auditSnapshot(*before)
+	}
+	if after != nil {
+		cur = auditSnapshot(*after)
+	}
+
+	changes := make(map[string]FieldChange)
+	for _, snapshot := range []map[string]interface{}{old, cur} {
+		for field := range snapshot {
+			if !reflect.DeepEqual(old[field], cur[field]) {
+				changes[field] = FieldChange{Before: old[field], After: cur[field]}
+			}
+		}
+	}
+	return changes
+}
+
+// audit grava o registro com o chamador, o request ID e o IP de origem.
+// Falhas são logadas e não interrompem a requisição já concluída.
+func (a *App) audit(r *http.Request, action, target string, changes map[string]FieldChange) {
+	entry := AuditEntry{
+		Action:    action,
+		Timestamp: time.Now(),
+		Actor:     "anonymous",
+		ActorType: "anonymous",
+		Target:    target,
+		RequestID: RequestIDFromContext(r.Context()),
+		IP:        clientIP(r, a.Config.TrustProxy),
+		Changes:   changes,
+	}
+	if caller, ok := CallerFromContext(r.Context()); ok {
+		entry.Actor = caller.Subject
+		entry.ActorType = "user"
+		if caller.APIKey {
+			entry.ActorType = "api_key"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditTimeout)
+	defer cancel()
+
+	if err := a.Audit.Insert(ctx, &entry); err != nil {
+		slog.ErrorContext(ctx, "erro ao gravar auditoria", "error", err, "action", action, "target", target)
+	}
+}
+
+// parseAuditQuery lê os filtros action, actor, target, from, to, limit e offset
+func parseAuditQuery(values url.Values) (AuditQuery, error) {
+	q := AuditQuery{
+		Action: strings.TrimSpace(values.Get("action")),
+		Actor:  strings.TrimSpace(values.Get("actor")),
+		Target: strings.TrimSpace(values.Get("target")),
+	}
+
+	var err error
+	if q.Limit, err = intParam(values, "limit", defaultPageLimit); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(values, "offset", 0); err != nil {
+		return q, err
+	}
+	if q.Limit < 1 || q.Limit > maxPageLimit {
+		return q, fmt.Errorf("limite deve estar entre 1 e %d", maxPageLimit)
+	}
+	if q.Offset < 0 {
+		return q, fmt.Errorf("parâmetro offset não pode ser negativo")
+	}
+	if q.From, err = optionalTimeParam(values, "from"); err != nil {
+		return q, err
+	}
+	if q.To, err = optionalTimeParam(values, "to"); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// Filter converte a consulta em filtro do MongoDB
+func (q AuditQuery) Filter() bson.M {
+	filter := bson.M{}
+	if q.Action != "" {
+		filter["action"] = q.Action
+	}
+	if q.Actor != "" {
+		filter["actor"] = q.Actor
+	}
+	if q.Target != "" {
+		filter["target"] = q.Target
+	}
+
+	timestamp := bson.M{}
+	if q.From != nil {
+		timestamp["$gte"] = *q.From
+	}
+	if q.To != nil {
+		timestamp["$lte"] = *q.To
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+	return filter
+}
+
+// Matches aplica o mesmo filtro de Filter em memória
+func (q AuditQuery) Matches(entry AuditEntry) bool {
+	switch {
+	case q.Action != "" && entry.Action != q.Action:
+		return false
+	case q.Actor != "" && entry.Actor != q.Actor:
+		return false
+	case q.Target != "" && entry.Target != q.Target:
+		return false
+	case q.From != nil && entry.Timestamp.Before(*q.From):
+		return false
+	case q.To != nil && entry.Timestamp.After(*q.To):
+		return false
+	}
+	return true
+}
+
+// AuditHandler lista a trilha de auditoria com filtros e paginação
+func (a *App) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	entries, total, err := a.Audit.List(ctx, query)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar auditoria", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"count":   len(entries),
+		"limit":   query.Limit,
+		"offset":  query.Offset,
+	})
+}
+
+// MongoAuditRepository grava na coleção "audit" criada pelos scripts de init
+type MongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAuditRepository cria o repositório sobre a coleção audit
+func NewMongoAuditRepository(db *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{collection: db.Collection("audit")}
+}
+
+// Insert grava um registro
+func (m *MongoAuditRepository) Insert(ctx context.Context, entry *AuditEntry) error {
+	result, err := m.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List retorna uma página de registros e o total do filtro
+func (m *MongoAuditRepository) List(ctx context.Context, query AuditQuery) ([]AuditEntry, int64, error) {
+	filter := query.Filter()
+
+	total, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// MemoryAuditRepository guarda a trilha em memória (USER_STORE=memory)
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []AuditEntry
+}
+
+// NewMemoryAuditRepository cria um repositório vazio
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// Insert grava um registro
+func (m *MemoryAuditRepository) Insert(ctx context.Context, entry *AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = primitive.NewObjectID()
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+// List retorna uma página de registros e o total do filtro
+func (m *MemoryAuditRepository) List(ctx context.Context, query AuditQuery) ([]AuditEntry, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	entries := []AuditEntry{}
+	for _, entry := range m.entries {
+		if query.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+
+	total := int64(len(entries))
+	if query.Offset >= len(entries) {
+		return []AuditEntry{}, total, nil
+	}
+	end := min(query.Offset+query.Limit, len(entries))
+	return entries[query.Offset:end], total, nil
+}
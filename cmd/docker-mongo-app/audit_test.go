@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditPage é a resposta de GET /audit
+type auditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int64        `json:"total"`
+	Count   int          `json:"count"`
+}
+
+// listAudit consulta GET /audit com o token de admin
+func listAudit(t *testing.T, app *App, admin string, query url.Values) auditPage {
+	t.Helper()
+	w := doRequest(app, http.MethodGet, "/audit?"+query.Encode(), "", "Authorization", admin)
+	expectStatus(t, w, http.StatusOK)
+	var page auditPage
+	decodeBody(t, w, &page)
+	return page
+}
+
+func TestAuditTrail(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	adminID, operatorID := primitive.NewObjectID(), primitive.NewObjectID()
+	admin := bearer(User{ID: adminID, Email: "admin@example.com", Roles: []string{RoleAdmin}})
+	operator := bearer(User{ID: operatorID, Email: "operator@example.com", Roles: []string{RoleOperator}})
+
+	w := doRequest(app, http.MethodPost, "/users", `{"name":"Alvo","email":"alvo@example.com","age":30}`,
+		"Authorization", admin, "X-Request-ID", "req-create-0001")
+	expectStatus(t, w, http.StatusCreated)
+	var target User
+	decodeBody(t, w, &target)
+	path := "/users/" + target.ID.Hex()
+
+	time.Sleep(5 * time.Millisecond)
+	cut := time.Now().UTC()
+	time.Sleep(5 * time.Millisecond)
+
+	expectStatus(t, doRequest(app, http.MethodPatch, path, `{"age":31}`,
+		"Authorization", operator, "X-Request-ID", "req-update-0001"), http.StatusOK)
+	// Sem alteração não há registro
+	expectStatus(t, doRequest(app, http.MethodPatch, path, `{"age":31}`, "Authorization", operator), http.StatusOK)
+	expectStatus(t, doRequest(app, http.MethodDelete, path, "",
+		"Authorization", admin, "X-Request-ID", "req-delete-0001"), http.StatusNoContent)
+
+	page := listAudit(t, app, admin, nil)
+	if page.Total != 3 || page.Count != 3 {
+		t.Fatalf("total=%d count=%d, esperado 3", page.Total, page.Count)
+	}
+
+	// Mais recentes primeiro
+	want := []struct {
+		action, actor, requestID string
+		changes                  map[string]FieldChange
+	}{
+		{AuditUserDelete, adminID.Hex(), "req-delete-0001", map[string]FieldChange{
+			"name": {Before: "Alvo"}, "email": {Before: "alvo@example.com"}, "age": {Before: 31.0},
+		}},
+		{AuditUserUpdate, operatorID.Hex(), "req-update-0001", map[string]FieldChange{
+			"age": {Before: 30.0, After: 31.0},
+		}},
+		{AuditUserCreate, adminID.Hex(), "req-create-0001", map[string]FieldChange{
+			"name": {After: "Alvo"}, "email": {After: "alvo@example.com"}, "age": {After: 30.0},
+		}},
+	}
+	for i, entry := range page.Entries {
+		w := want[i]
+		if entry.Action != w.action || entry.Actor != w.actor || entry.ActorType != "user" ||
+			entry.RequestID != w.requestID || entry.Target != target.ID.Hex() || entry.IP == "" {
+			t.Fatalf("registro %d inesperado: %+v", i, entry)
+		}
+		for field, change := range w.changes {
+			if got := entry.Changes[field]; got != change {
+				t.Errorf("%s: %s = %+v, esperado %+v", entry.Action, field, got, change)
+			}
+		}
+		if _, ok := entry.Changes["password_hash"]; ok {
+			t.Errorf("%s expõe o hash da senha", entry.Action)
+		}
+	}
+	if len(page.Entries[1].Changes) != 1 {
+		t.Errorf("update registrou campos não alterados: %+v", page.Entries[1].Changes)
+	}
+
+	// Filtros
+	filters := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{"action", url.Values{"action": {AuditUserUpdate}}, []string{AuditUserUpdate}},
+		{"actor", url.Values{"actor": {adminID.Hex()}}, []string{AuditUserDelete, AuditUserCreate}},
+		{"actor sem registros", url.Values{"actor": {"ninguem"}}, nil},
+		{"target", url.Values{"target": {target.ID.Hex()}}, []string{AuditUserDelete, AuditUserUpdate, AuditUserCreate}},
+		{"target sem registros", url.Values{"target": {primitive.NewObjectID().Hex()}}, nil},
+		{"from", url.Values{"from": {cut.Format(time.RFC3339Nano)}}, []string{AuditUserDelete, AuditUserUpdate}},
+		{"to", url.Values{"to": {cut.Format(time.RFC3339Nano)}}, []string{AuditUserCreate}},
+		{"actor e from", url.Values{"actor": {adminID.Hex()}, "from": {cut.Format(time.RFC3339Nano)}}, []string{AuditUserDelete}},
+		{"paginação", url.Values{"limit": {"1"}, "offset": {"1"}}, []string{AuditUserUpdate}},
+	}
+	for _, f := range filters {
+		t.Run(f.name, func(t *testing.T) {
+			page := listAudit(t, app, admin, f.query)
+			var got []string
+			for _, entry := range page.Entries {
+				got = append(got, entry.Action)
+			}
+			if len(got) != len(f.want) {
+				t.Fatalf("ações = %v, esperado %v", got, f.want)
+			}
+			for i := range got {
+				if got[i] != f.want[i] {
+					t.Fatalf("ações = %v, esperado %v", got, f.want)
+				}
+			}
+		})
+	}
+
+	expectStatus(t, doRequest(app, http.MethodGet, "/audit?from=ontem", "", "Authorization", admin), http.StatusBadRequest)
+	expectStatus(t, doRequest(app, http.MethodGet, "/audit?limit=0", "", "Authorization", admin), http.StatusBadRequest)
+}
+
+// Atualizações concorrentes: cada "before" precisa ser o estado
+// realmente substituído, formando uma única cadeia de alterações
+func TestAuditConcurrentUpdates(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+
+	w := doRequest(app, http.MethodPost, "/users", `{"name":"Alvo","email":"alvo@example.com","age":100}`, "Authorization", admin)
+	expectStatus(t, w, http.StatusCreated)
+	var target User
+	decodeBody(t, w, &target)
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 1; i <= updates; i++ {
+		wg.Add(1)
+		go func(age int) {
+			defer wg.Done()
+			doRequest(app, http.MethodPatch, "/users/"+target.ID.Hex(), `{"age":`+strconv.Itoa(age)+`}`, "Authorization", admin)
+		}(i)
+	}
+	wg.Wait()
+
+	page := listAudit(t, app, admin, url.Values{"action": {AuditUserUpdate}, "limit": {"100"}})
+	if page.Total != updates {
+		t.Fatalf("%d atualizações auditadas, esperado %d", page.Total, updates)
+	}
+
+	// Cada valor é substituído uma única vez: 100 e as idades atribuídas,
+	// exceto a última, que permanece
+	replaced := make(map[float64]int)
+	for _, entry := range page.Entries {
+		replaced[entry.Changes["age"].Before.(float64)]++
+	}
+	final, err := app.Users.FindByID(context.Background(), target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced[100] != 1 || replaced[float64(final.Age)] != 0 {
+		t.Fatalf("cadeia inválida: %v (idade final %d)", replaced, final.Age)
+	}
+	for before, n := range replaced {
+		if n != 1 {
+			t.Fatalf("estado %v substituído %d vezes: %v", before, n, replaced)
+		}
+	}
+}
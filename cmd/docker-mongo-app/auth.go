@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ===========================================
+// AUTENTICAÇÃO (BEARER JWT)
+// ===========================================
+
+// publicRoutes são os templates de rota que não exigem autenticação
+var publicRoutes = map[string]bool{
+	"/":             true,
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+	"/openapi.json": true,
+	"/docs":         true,
+	"/auth/login":   true,
+	"/auth/refresh": true,
+	"/auth/logout":  true,
+}
+
+// ClaimsFromContext retorna as claims do token validado pelo AuthMiddleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// isPublicRoute verifica se a rota atual do mux dispensa autenticação
+func isPublicRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	tpl, err := route.GetPathTemplate()
+	return err == nil && publicRoutes[tpl]
+}
+
+// AuthMiddleware exige "Authorization: Bearer <jwt>" ou "X-API-Key: <chave>"
+// nas rotas não públicas quando AUTH_ENABLED=true. As claims do JWT ficam
+// disponíveis via ClaimsFromContext e o chamador (usuário ou chave de API,
+// com suas permissões) via CallerFromContext.
+func (a *App) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.JWT == nil || r.Method == http.MethodOptions || isPublicRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if secret := r.Header.Get(apiKeyHeader); secret != "" {
+			caller, err := a.authenticateAPIKey(r, secret)
+			if errors.Is(err, ErrAPIKeyNotFound) {
+				writeError(w, r, http.StatusUnauthorized, "Chave de API inválida, expirada ou revogada")
+				return
+			}
+			if err != nil {
+				writeRepositoryError(w, r, "Erro ao validar chave de API", err)
+				return
+			}
+
+			recordActor(r.Context(), caller.Subject)
+			ctx := context.WithValue(r.Context(), callerKey, caller)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		header := r.Header.Get("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, r, http.StatusUnauthorized, "Token de acesso ausente")
+			return
+		}
+
+		claims, err := a.JWT.Verify(strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="api", error="invalid_token", error_description=%q`, err.Error()))
+			writeError(w, r, http.StatusUnauthorized, "Token inválido: "+err.Error())
+			return
+		}
+
+		recordActor(r.Context(), claims.Subject)
+		ctx := context.WithValue(r.Context(), claimsKey, claims)
+		ctx = context.WithValue(ctx, callerKey, NewCaller(claims.Subject, claims.Email, claims.Roles))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
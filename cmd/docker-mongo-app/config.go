@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// CONFIGURAÇÃO
+// ===========================================
+
+// Config representa as configurações da aplicação, já convertidas e
+// validadas por LoadConfig
+type Config struct {
+	Environment     string
+	EnvFile         string // arquivo .env carregado; vazio se nenhum
+	AppName         string
+	AppPort         int
+	AppHost         string
+	MongoURI        string
+	MongoHost       string
+	MongoPort       int
+	MongoDatabase   string
+	Debug           bool
+	LogLevel        string
+	APITimeout      time.Duration
+	ShutdownTimeout time.Duration
+	EnableCORS      bool
+	AllowOrigins    []string
+	UserStore       string
+	ConfigEndpoint  string // GET /config: enabled ou disabled
+
+	// CORS (além de ENABLE_CORS e ALLOW_ORIGINS)
+	CORSAllowMethods     []string
+	CORSAllowHeaders     []string
+	CORSExposeHeaders    []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// Retry da conexão com o MongoDB
+	MongoConnectRetries  int
+	MongoRetryBackoff    time.Duration
+	MongoRetryMaxBackoff time.Duration
+	StartDegraded        bool
+
+	// Autenticação JWT
+	AuthEnabled      bool
+	JWTSecret        string
+	JWTPublicKey     string
+	JWTPublicKeyFile string
+	JWTIssuer        string
+	JWTAudience      string
+
+	// Login com senha
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	MaxLoginAttempts int
+	LoginLockout     time.Duration
+
+	// Rate limiting
+	RateLimitEnabled      bool
+	RateLimitDefault      RateLimit
+	RateLimitRoutes       map[string]RateLimit // chave: "MÉTODO /template"
+	RateLimitAuthFailures RateLimit            // respostas 401 por IP
+	RateLimitIdleTTL      time.Duration
+	TrustProxy            bool
+
+	// Logs de requisições na coleção logs
+	LogSinkEnabled       bool
+	LogRetention         time.Duration
+	LogSinkBatchSize     int
+	LogSinkFlushInterval time.Duration
+	LogSinkBuffer        int
+}
+
+// minJWTSecretLength é o tamanho mínimo de JWT_SECRET: 256 bits, o tamanho
+// da saída do HMAC-SHA256 (RFC 7518, seção 3.2)
+const minJWTSecretLength = 32
+
+// envFileNames associa ENV ao arquivo lido quando ENV_FILE não é informado
+var envFileNames = map[string]string{
+	"development":  ".env.dev",
+	"homologation": ".env.hml",
+	"production":   ".env.prod",
+}
+
+// ConfigErrors agrupa todos os problemas encontrados na configuração, para
+// que sejam corrigidos de uma vez em vez de um a cada inicialização
+type ConfigErrors []string
+
+func (e ConfigErrors) Error() string {
+	return "configuração inválida:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// add registra um problema
+func (e *ConfigErrors) add(format string, args ...interface{}) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+// LoadConfig carrega a configuração do arquivo .env do ambiente (ENV_FILE
+// ou .env.dev/.env.hml/.env.prod conforme ENV, no diretório atual), com as
+// variáveis de ambiente do sistema tendo precedência sobre o arquivo. O
+// arquivo é opcional: no Docker o compose já injeta as variáveis.
+// Retorna ConfigErrors com todos os valores inválidos.
+func LoadConfig() (*Config, error) {
+	env := strings.TrimSpace(os.Getenv("ENV"))
+	if env == "" {
+		env = "development"
+	}
+
+	l := &configLoader{}
+	path, explicit := os.LookupEnv("ENV_FILE")
+	if !explicit {
+		path = envFileNames[env]
+	}
+	if path != "" {
+		values, err := readEnvFile(path)
+		switch {
+		case err == nil:
+			l.file = values
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+			path = ""
+		default:
+			l.errs.add("ENV_FILE: %v", err)
+			path = ""
+		}
+	}
+
+	config := &Config{
+		Environment:     env,
+		EnvFile:         path,
+		AppName:         l.string("APP_NAME", "go-mongo-app"),
+		AppPort:         l.port("APP_PORT", 8080),
+		AppHost:         l.string("APP_HOST", "0.0.0.0"),
+		MongoURI:        l.secret("MONGO_URI", "mongodb://localhost:27017/app_development"),
+		MongoHost:       l.string("MONGO_HOST", "localhost"),
+		MongoPort:       l.port("MONGO_PORT", 27017),
+		MongoDatabase:   l.string("MONGO_DATABASE", "app_development"),
+		Debug:           l.bool("DEBUG", true),
+		LogLevel:        strings.ToLower(l.string("LOG_LEVEL", "debug")),
+		APITimeout:      l.duration("API_TIMEOUT", 30*time.Second),
+		ShutdownTimeout: l.duration("SHUTDOWN_TIMEOUT", 10*time.Second),
+		EnableCORS:      l.bool("ENABLE_CORS", true),
+		AllowOrigins:    l.list("ALLOW_ORIGINS", "*"),
+		UserStore:       l.string("USER_STORE", "mongo"),
+
+		CORSAllowMethods:     l.list("CORS_ALLOW_METHODS", "GET,POST,PUT,PATCH,DELETE"),
+		CORSAllowHeaders:     l.list("CORS_ALLOW_HEADERS", "Content-Type,Authorization,X-API-Key,X-Request-ID,traceparent"),
+		CORSExposeHeaders:    l.list("CORS_EXPOSE_HEADERS", "X-Request-ID,traceparent,RateLimit-Policy,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"),
+		CORSAllowCredentials: l.bool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           l.duration("CORS_MAX_AGE", 10*time.Minute),
+
+		MongoConnectRetries:  l.int("MONGO_CONNECT_RETRIES", 5, 0),
+		MongoRetryBackoff:    l.duration("MONGO_RETRY_BACKOFF", time.Second),
+		MongoRetryMaxBackoff: l.duration("MONGO_RETRY_MAX_BACKOFF", 30*time.Second),
+		StartDegraded:        l.bool("START_DEGRADED", false),
+
+		AuthEnabled:      l.bool("AUTH_ENABLED", false),
+		JWTSecret:        l.secret("JWT_SECRET", ""),
+		JWTPublicKey:     l.string("JWT_PUBLIC_KEY", ""),
+		JWTPublicKeyFile: l.string("JWT_PUBLIC_KEY_FILE", ""),
+		JWTIssuer:        l.string("JWT_ISSUER", ""),
+		JWTAudience:      l.string("JWT_AUDIENCE", ""),
+
+		AccessTokenTTL:   l.duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:  l.duration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		MaxLoginAttempts: l.int("MAX_LOGIN_ATTEMPTS", 5, 0),
+		LoginLockout:     l.duration("LOGIN_LOCKOUT", 15*time.Minute),
+
+		RateLimitEnabled:      l.bool("RATE_LIMIT_ENABLED", true),
+		RateLimitDefault:      l.rateLimit("RATE_LIMIT_DEFAULT", "300/1m"),
+		RateLimitRoutes:       l.rateLimitRoutes("RATE_LIMIT_ROUTES", "POST /users=30/1m,POST /auth/login=10/1m"),
+		RateLimitAuthFailures: l.rateLimit("RATE_LIMIT_AUTH_FAILURES", "20/1m"),
+		RateLimitIdleTTL:      l.duration("RATE_LIMIT_IDLE_TTL", 10*time.Minute),
+		TrustProxy:            l.bool("TRUST_PROXY", false),
+
+		LogSinkEnabled:       l.bool("LOG_SINK_ENABLED", true),
+		LogRetention:         l.duration("LOG_RETENTION", 7*24*time.Hour),
+		LogSinkBatchSize:     l.int("LOG_SINK_BATCH_SIZE", 100, 1),
+		LogSinkFlushInterval: l.duration("LOG_SINK_FLUSH_INTERVAL", 2*time.Second),
+		LogSinkBuffer:        l.int("LOG_SINK_BUFFER", 1000, 1),
+	}
+
+	// Em produção /config fica desligado, salvo configuração explícita
+	defaultConfigEndpoint := "enabled"
+	if env == "production" {
+		defaultConfigEndpoint = "disabled"
+	}
+	config.ConfigEndpoint = l.string("CONFIG_ENDPOINT", defaultConfigEndpoint)
+
+	config.validate(&l.errs)
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+	return config, nil
+}
+
+// validate verifica valores permitidos e dependências entre variáveis
+func (c *Config) validate(errs *ConfigErrors) {
+	if _, ok := envFileNames[c.Environment]; !ok {
+		errs.add("ENV: %q inválido (use development, homologation ou production)", c.Environment)
+	}
+	if c.AppName == "" {
+		errs.add("APP_NAME: obrigatório")
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs.add("LOG_LEVEL: %q inválido (use debug, info, warn ou error)", c.LogLevel)
+	}
+
+	switch c.UserStore {
+	case "memory":
+	case "mongo":
+		if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+			errs.add("MONGO_URI: deve começar com mongodb:// ou mongodb+srv://")
+		}
+		if c.MongoDatabase == "" {
+			errs.add("MONGO_DATABASE: obrigatório com USER_STORE=mongo")
+		}
+		if c.MongoRetryBackoff > c.MongoRetryMaxBackoff {
+			errs.add("MONGO_RETRY_BACKOFF: %s maior que MONGO_RETRY_MAX_BACKOFF (%s)", c.MongoRetryBackoff, c.MongoRetryMaxBackoff)
+		}
+	default:
+		errs.add("USER_STORE: %q inválido (use mongo ou memory)", c.UserStore)
+	}
+
+	switch c.ConfigEndpoint {
+	case "enabled", "disabled":
+	default:
+		errs.add("CONFIG_ENDPOINT: %q inválido (use enabled ou disabled)", c.ConfigEndpoint)
+	}
+
+	if c.AuthEnabled && c.JWTSecret == "" && c.JWTPublicKey == "" && c.JWTPublicKeyFile == "" {
+		errs.add("AUTH_ENABLED=true exige JWT_SECRET, JWT_PUBLIC_KEY ou JWT_PUBLIC_KEY_FILE")
+	}
+	if c.JWTSecret != "" {
+		// Quem conhece o segredo HS256 assina tokens com qualquer papel
+		if len(c.JWTSecret) < minJWTSecretLength {
+			errs.add("JWT_SECRET: muito curto (%d bytes; mínimo %d para HS256)", len(c.JWTSecret), minJWTSecretLength)
+		}
+		if isPlaceholderSecret(c.JWTSecret) {
+			errs.add("JWT_SECRET: valor de exemplo (*_change_me); gere um segredo aleatório")
+		}
+	}
+
+	if c.EnableCORS {
+		c.validateCORS(errs)
+	}
+}
+
+// isPlaceholderSecret reconhece valores de exemplo como "prod_secret_change_me"
+func isPlaceholderSecret(secret string) bool {
+	secret = strings.ToLower(secret)
+	return strings.HasSuffix(secret, "change_me") || strings.HasSuffix(secret, "changeme")
+}
+
+// validateCORS verifica as origens e a combinação com credenciais
+func (c *Config) validateCORS(errs *ConfigErrors) {
+	if len(c.AllowOrigins) == 0 {
+		errs.add("ALLOW_ORIGINS: obrigatório com ENABLE_CORS=true")
+	}
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			// Com credenciais o navegador recusa "*" e ecoar qualquer
+			// origem exporia os cookies/tokens a qualquer site
+			if c.CORSAllowCredentials {
+				errs.add("ALLOW_ORIGINS: \"*\" não é permitido com CORS_ALLOW_CREDENTIALS=true; liste as origens")
+			}
+			continue
+		}
+		if _, err := parseOriginPattern(origin); err != nil {
+			errs.add("ALLOW_ORIGINS: %v", err)
+		}
+	}
+	for _, method := range c.CORSAllowMethods {
+		if !isHTTPToken(method) {
+			errs.add("CORS_ALLOW_METHODS: método %q inválido", method)
+		}
+	}
+	for _, header := range c.CORSAllowHeaders {
+		if header != "*" && !isHTTPToken(header) {
+			errs.add("CORS_ALLOW_HEADERS: header %q inválido", header)
+		}
+	}
+}
+
+// isHTTPToken aceita nomes de métodos e headers (letras, dígitos e -_.)
+func isHTTPToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// configLoader lê cada variável do sistema ou do arquivo .env, convertendo
+// para o tipo do campo e acumulando os erros
+type configLoader struct {
+	file map[string]string
+	errs ConfigErrors
+}
+
+// lookup retorna o valor do sistema (se não vazio) ou do arquivo
+func (l *configLoader) lookup(key string) (string, bool) {
+	if value := os.Getenv(key); value != "" {
+		return value, true
+	}
+	value, ok := l.file[key]
+	return value, ok && value != ""
+}
+
+func (l *configLoader) string(key, defaultValue string) string {
+	if value, ok := l.lookup(key); ok {
+		return strings.TrimSpace(value)
+	}
+	return defaultValue
+}
+
+func (l *configLoader) bool(key string, defaultValue bool) bool {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		l.errs.add("%s: %q inválido (use true ou false)", key, value)
+		return defaultValue
+	}
+	return b
+}
+
+// int lê um inteiro maior ou igual a minimum
+func (l *configLoader) int(key string, defaultValue, minimum int) int {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < minimum {
+		l.errs.add("%s: %q inválido (inteiro maior ou igual a %d)", key, value, minimum)
+		return defaultValue
+	}
+	return n
+}
+
+func (l *configLoader) port(key string, defaultValue int) int {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 1 || n > 65535 {
+		l.errs.add("%s: %q inválido (porta entre 1 e 65535)", key, value)
+		return defaultValue
+	}
+	return n
+}
+
+// duration lê valores como "30s" ou "2m"; precisa ser positiva
+func (l *configLoader) duration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := l.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d <= 0 {
+		l.errs.add("%s: %q inválido (duração positiva, ex.: 30s, 5m, 168h)", key, value)
+		return defaultValue
+	}
+	return d
+}
+
+// secret lê um segredo de KEY ou do arquivo indicado em KEY_FILE (Docker
+// secrets, ex.: /run/secrets/mongo_uri). As variáveis do sistema têm
+// precedência sobre o arquivo .env; definir KEY e KEY_FILE na mesma origem
+// é erro, para não haver dúvida sobre qual valor vale.
+func (l *configLoader) secret(key, defaultValue string) string {
+	fileKey := key + "_FILE"
+	for _, source := range []map[string]string{l.osValues(key, fileKey), l.file} {
+		value, path := source[key], source[fileKey]
+		switch {
+		case value != "" && path != "":
+			l.errs.add("%s: defina %s ou %s, não ambos", key, key, fileKey)
+			return defaultValue
+		case path != "":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				l.errs.add("%s: %v", fileKey, err)
+				return defaultValue
+			}
+			return strings.TrimSpace(string(data))
+		case value != "":
+			return strings.TrimSpace(value)
+		}
+	}
+	return defaultValue
+}
+
+// osValues retorna as variáveis do sistema informadas (não vazias)
+func (l *configLoader) osValues(keys ...string) map[string]string {
+	values := make(map[string]string)
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// list lê valores separados por vírgula, ignorando itens vazios
+func (l *configLoader) list(key, defaultValue string) []string {
+	value, ok := l.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (l *configLoader) rateLimit(key, defaultValue string) RateLimit {
+	limit, err := parseRateLimit(l.string(key, defaultValue))
+	if err != nil {
+		l.errs.add("%s: %v", key, err)
+	}
+	return limit
+}
+
+func (l *configLoader) rateLimitRoutes(key, defaultValue string) map[string]RateLimit {
+	routes, err := parseRateLimitRoutes(l.string(key, defaultValue))
+	if err != nil {
+		l.errs.add("%s: %v", key, err)
+	}
+	return routes
+}
+
+// readEnvFile lê um arquivo no formato CHAVE=valor. Linhas vazias e
+// comentários (#) são ignorados; aceita o prefixo "export" e valores entre
+// aspas simples ou duplas.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("%s:%d: linha inválida (use CHAVE=valor)", path, line)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
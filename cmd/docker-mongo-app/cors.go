@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ===========================================
+// CORS
+// ===========================================
+
+// O navegador envia um preflight (OPTIONS com Access-Control-Request-Method)
+// antes de requisições com Authorization, JSON ou métodos além de GET/POST.
+// Como as rotas são registradas com .Methods(...), SetupRoutes registra uma
+// rota OPTIONS para cada template (registerOptionsRoutes); assim o preflight
+// passa pelos middlewares e o CORSMiddleware responde com os métodos da rota.
+
+// originPattern é uma origem permitida: exata ("https://app.example.com") ou
+// com curinga de subdomínio ("https://*.example.com")
+type originPattern struct {
+	scheme string // "https"
+	host   string // "app.example.com" ou, com curinga, ".example.com"
+	port   string
+	wild   bool
+}
+
+// parseOriginPattern valida uma entrada de ALLOW_ORIGINS
+func parseOriginPattern(value string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(strings.TrimSuffix(value, "/")))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("origem %q inválida (use esquema://host[:porta], ex.: https://app.example.com)", value)
+	}
+
+	p := originPattern{scheme: u.Scheme, host: u.Hostname(), port: u.Port()}
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.wild, p.host = true, "."+rest
+	}
+	if strings.Contains(p.host, "*") || (p.wild && !strings.Contains(p.host[1:], ".")) {
+		return originPattern{}, fmt.Errorf("origem %q inválida (curinga apenas no início, ex.: https://*.example.com)", value)
+	}
+	return p, nil
+}
+
+// matches verifica se a origem enviada pelo navegador atende ao padrão.
+// O curinga exige ao menos um subdomínio: "https://*.example.com" não
+// aceita "https://example.com".
+func (p originPattern) matches(origin *url.URL) bool {
+	if origin.Scheme != p.scheme || origin.Port() != p.port {
+		return false
+	}
+	host := origin.Hostname()
+	if p.wild {
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+// CORSPolicy é a política montada a partir de ALLOW_ORIGINS e CORS_*
+type CORSPolicy struct {
+	allowAll      bool
+	origins       []originPattern
+	methods       []string
+	headers       []string // em minúsculas; "*" aceita qualquer header
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// NewCORSPolicy retorna nil quando ENABLE_CORS=false. Os valores já foram
+// validados por LoadConfig.
+func NewCORSPolicy(config *Config) *CORSPolicy {
+	if !config.EnableCORS {
+		return nil
+	}
+
+	p := &CORSPolicy{
+		exposeHeaders: strings.Join(config.CORSExposeHeaders, ", "),
+		credentials:   config.CORSAllowCredentials,
+		maxAge:        strconv.Itoa(int(config.CORSMaxAge.Seconds())),
+	}
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			p.allowAll = true
+			continue
+		}
+		if pattern, err := parseOriginPattern(origin); err == nil {
+			p.origins = append(p.origins, pattern)
+		}
+	}
+	for _, method := range config.CORSAllowMethods {
+		p.methods = append(p.methods, strings.ToUpper(method))
+	}
+	for _, header := range config.CORSAllowHeaders {
+		p.headers = append(p.headers, strings.ToLower(header))
+	}
+	return p
+}
+
+// allowOrigin verifica a origem contra a lista de permitidas
+func (p *CORSPolicy) allowOrigin(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, pattern := range p.origins {
+		if pattern.matches(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowHeaders verifica os headers pedidos no preflight e retorna o valor
+// de Access-Control-Allow-Headers
+func (p *CORSPolicy) allowHeaders(requested string) (string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return "", true
+	}
+	if slices.Contains(p.headers, "*") {
+		return requested, true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		if !slices.Contains(p.headers, strings.ToLower(strings.TrimSpace(header))) {
+			return "", false
+		}
+	}
+	return requested, true
+}
+
+// routeMethods retorna os métodos registrados para o template da rota atual
+func (a *App) routeMethods(r *http.Request) []string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return a.optionsRoutes[tpl]
+		}
+	}
+	return nil
+}
+
+// CORSMiddleware aplica a política CORS. Respostas sempre levam
+// "Vary: Origin", já que os headers dependem da origem; origens fora da
+// lista não recebem headers CORS e o navegador bloqueia a resposta. O
+// preflight é respondido aqui, sem passar pela autenticação e pelo rate
+// limit.
+func (a *App) CORSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := a.CORS
+		if p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" || !p.allowOrigin(origin) {
+			if origin != "" {
+				slog.DebugContext(r.Context(), "origem CORS não permitida", "origin", origin)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.allowAll && !p.credentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if p.exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Métodos aceitos: os da rota que também estão em CORS_ALLOW_METHODS
+		var methods []string
+		for _, method := range a.routeMethods(r) {
+			if slices.Contains(p.methods, method) {
+				methods = append(methods, method)
+			}
+		}
+		requestedMethod := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		headers, headersOK := p.allowHeaders(r.Header.Get("Access-Control-Request-Headers"))
+
+		if !slices.Contains(methods, requestedMethod) || !headersOK {
+			// Preflight recusado: sem Allow-Methods/Allow-Headers o
+			// navegador não envia a requisição
+			slog.DebugContext(r.Context(), "preflight CORS recusado",
+				"origin", origin, "method", requestedMethod,
+				"headers", r.Header.Get("Access-Control-Request-Headers"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", p.maxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// registerOptionsRoutes registra OPTIONS em cada template já registrado,
+// para que o mux não responda 405 ao preflight antes dos middlewares.
+// Deve ser chamado depois de todas as rotas.
+func (a *App) registerOptionsRoutes() {
+	a.optionsRoutes = make(map[string][]string)
+	var templates []string
+	a.Router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		if _, seen := a.optionsRoutes[tpl]; !seen {
+			templates = append(templates, tpl)
+		}
+		a.optionsRoutes[tpl] = append(a.optionsRoutes[tpl], methods...)
+		return nil
+	})
+
+	for _, tpl := range templates {
+		a.Router.HandleFunc(tpl, a.OptionsHandler).Methods(http.MethodOptions)
+	}
+}
+
+// OptionsHandler responde OPTIONS fora de um preflight CORS (ou com CORS
+// desabilitado) informando os métodos da rota no header Allow
+func (a *App) OptionsHandler(w http.ResponseWriter, r *http.Request) {
+	methods := append(slices.Clone(a.routeMethods(r)), http.MethodOptions)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
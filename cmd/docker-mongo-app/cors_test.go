@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testOrigins = "https://*.example.com,http://localhost:3000,https://app.test:8443"
+
+// expectVary confere os valores de Vary da resposta
+func expectVary(t *testing.T, header http.Header, want ...string) {
+	t.Helper()
+	if got := header.Values("Vary"); !slices.Equal(got, want) {
+		t.Fatalf("Vary = %q, esperado %q", got, want)
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	app := newTestApp(t, map[string]string{"ALLOW_ORIGINS": testOrigins})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://api.example.com", true},
+		{"https://a.b.example.com", true},
+		{"HTTPS://API.EXAMPLE.COM", true},
+		// O curinga exige subdomínio e não casa por sufixo de texto
+		{"https://example.com", false},
+		{"https://evilexample.com", false},
+		{"https://api.example.com.evil.com", false},
+		// Esquema e porta precisam ser os mesmos do padrão
+		{"http://api.example.com", false},
+		{"https://api.example.com:8443", false},
+		{"http://localhost:3000", true},
+		{"https://localhost:3000", false},
+		{"http://localhost:3001", false},
+		{"http://localhost", false},
+		{"https://app.test:8443", true},
+		{"https://app.test", false},
+		{"https://app.test:443", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			w := doRequest(app, http.MethodGet, "/health", "", "Origin", tt.origin)
+			expectStatus(t, w, http.StatusOK)
+			expectVary(t, w.Header(), "Origin")
+
+			got := w.Header().Get("Access-Control-Allow-Origin")
+			switch {
+			case tt.allowed && got != tt.origin:
+				t.Fatalf("Access-Control-Allow-Origin = %q, esperado %q", got, tt.origin)
+			case !tt.allowed && got != "":
+				t.Fatalf("origem recusada recebeu Access-Control-Allow-Origin = %q", got)
+			}
+			if exposed := w.Header().Get("Access-Control-Expose-Headers"); tt.allowed != (exposed != "") {
+				t.Fatalf("Access-Control-Expose-Headers = %q", exposed)
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	// "*" com credenciais é recusado na configuração
+	t.Setenv("ENV_FILE", "")
+	t.Setenv("USER_STORE", "memory")
+	t.Setenv("ALLOW_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	_, err := LoadConfig()
+	expectError(t, err, `ALLOW_ORIGINS: "*" não é permitido com CORS_ALLOW_CREDENTIALS=true`)
+
+	// "*" sem credenciais responde o curinga literal
+	app := newTestApp(t, map[string]string{"ALLOW_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "false"})
+	w := doRequest(app, http.MethodGet, "/health", "", "Origin", "https://qualquer.site")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, esperado *", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("Access-Control-Allow-Credentials = %q sem credenciais", got)
+	}
+
+	// Com credenciais a origem é ecoada, nunca "*"
+	app = newTestApp(t, map[string]string{"ALLOW_ORIGINS": testOrigins, "CORS_ALLOW_CREDENTIALS": "true"})
+	w = doRequest(app, http.MethodGet, "/health", "", "Origin", "https://api.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://api.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("Access-Control-Allow-Credentials = %q", got)
+	}
+	expectVary(t, w.Header(), "Origin")
+
+	w = doRequest(app, http.MethodGet, "/health", "", "Origin", "https://example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("origem recusada recebeu headers CORS: %v", w.Header())
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"ALLOW_ORIGINS": testOrigins,
+		"AUTH_ENABLED":  "true",
+		"JWT_SECRET":    testJWTSecret,
+		"CORS_MAX_AGE":  "10m",
+	})
+	preflightVary := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+
+	preflight := func(path, origin, method, headers string) *http.Response {
+		t.Helper()
+		w := doRequest(app, http.MethodOptions, path, "",
+			"Origin", origin,
+			"Access-Control-Request-Method", method,
+			"Access-Control-Request-Headers", headers)
+		// O preflight não passa pela autenticação
+		expectStatus(t, w, http.StatusNoContent)
+		expectVary(t, w.Header(), preflightVary...)
+		return w.Result()
+	}
+
+	// Aceito: apenas os métodos da rota, sem exigir token
+	resp := preflight("/users/abc", "https://api.example.com", "DELETE", "Authorization, Content-Type")
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "GET, PUT, PATCH, DELETE" {
+		t.Fatalf("Access-Control-Allow-Methods = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+		t.Fatalf("Access-Control-Allow-Headers = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("Access-Control-Max-Age = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://api.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+
+	// Recusados: o navegador não envia a requisição sem Allow-Methods
+	refused := []struct {
+		name, path, origin, method, headers string
+	}{
+		{"método ausente na rota", "/users/search", "https://api.example.com", "DELETE", ""},
+		{"método ausente em /health", "/health", "https://api.example.com", "POST", ""},
+		{"header não permitido", "/users", "https://api.example.com", "POST", "Content-Type, X-Evil"},
+		{"origem não permitida", "/users", "https://evilexample.com", "POST", ""},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := preflight(tt.path, tt.origin, tt.method, tt.headers)
+			for _, header := range []string{"Access-Control-Allow-Methods", "Access-Control-Allow-Headers", "Access-Control-Max-Age"} {
+				if got := resp.Header.Get(header); got != "" {
+					t.Fatalf("%s = %q em preflight recusado", header, got)
+				}
+			}
+		})
+	}
+
+	// OPTIONS sem Access-Control-Request-Method não é preflight
+	w := doRequest(app, http.MethodOptions, "/users", "", "Origin", "https://api.example.com")
+	expectStatus(t, w, http.StatusNoContent)
+	expectVary(t, w.Header(), "Origin")
+	if got := w.Header().Get("Allow"); !strings.Contains(got, "POST") || !strings.HasSuffix(got, "OPTIONS") {
+		t.Fatalf("Allow = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Fatalf("Access-Control-Allow-Methods = %q fora de preflight", got)
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	app := newTestApp(t, map[string]string{"ENABLE_CORS": "false"})
+
+	w := doRequest(app, http.MethodOptions, "/users/abc", "",
+		"Origin", "https://api.example.com",
+		"Access-Control-Request-Method", "DELETE")
+	expectStatus(t, w, http.StatusNoContent)
+	if got := w.Header().Get("Allow"); got != "GET, PUT, PATCH, DELETE, OPTIONS" {
+		t.Fatalf("Allow = %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || len(w.Header().Values("Vary")) != 0 {
+		t.Fatalf("headers CORS com ENABLE_CORS=false: %v", w.Header())
+	}
+}
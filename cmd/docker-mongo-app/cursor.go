@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ===========================================
+// PAGINAÇÃO POR CURSOR (KEYSET)
+// ===========================================
+
+// A paginação por cursor usa a ordem fixa created_at DESC, _id DESC,
+// aproveitando o índice { created_at: -1 }. Em vez de pular documentos
+// (skip), cada página continua a partir do último documento da anterior,
+// então o custo não cresce com a posição e inserções não deslocam páginas.
+
+// userCursor é a posição do último usuário retornado em uma página
+type userCursor struct {
+	CreatedAt time.Time          `json:"c"`
+	ID        primitive.ObjectID `json:"i"`
+}
+
+// cursorSort é a ordenação usada no modo cursor
+var cursorSort = bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+
+// encodeCursor gera o token opaco enviado ao cliente em next_cursor
+func encodeCursor(user User) string {
+	data, _ := json.Marshal(userCursor{CreatedAt: user.CreatedAt, ID: user.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor valida e decodifica um token recebido em ?cursor=
+func decodeCursor(token string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("cursor inválido")
+	}
+
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, fmt.Errorf("cursor inválido")
+	}
+	return &cursor, nil
+}
+
+// cursorFilter restringe o filtro aos documentos depois do cursor
+func cursorFilter(filter bson.M, after *userCursor) bson.M {
+	if after == nil {
+		return filter
+	}
+
+	keyset := bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{"$lt": after.CreatedAt}},
+		bson.M{"created_at": after.CreatedAt, "_id": bson.M{"$lt": after.ID}},
+	}}
+	if len(filter) == 0 {
+		return keyset
+	}
+	return bson.M{"$and": bson.A{filter, keyset}}
+}
+
+// listUsersByCursor responde GET /users no modo cursor
+func (a *App) listUsersByCursor(w http.ResponseWriter, r *http.Request, query UserListQuery) {
+	// Busca um documento a mais para saber se existe próxima página
+	page := query
+	page.Limit = query.Limit + 1
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	users, err := a.Users.ListAfter(ctx, page)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuários", err)
+		return
+	}
+
+	var nextCursor, nextLink interface{}
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
+		token := encodeCursor(users[len(users)-1])
+		nextCursor = token
+
+		values := r.URL.Query()
+		values.Set("cursor", token)
+		values.Set("limit", fmt.Sprint(query.Limit))
+		nextLink = r.URL.Path + "?" + values.Encode()
+	}
+
+	response := map[string]interface{}{
+		"users":       users,
+		"count":       len(users),
+		"limit":       query.Limit,
+		"next_cursor": nextCursor,
+		"links":       map[string]interface{}{"next": nextLink},
+		"environment": a.Config.Environment,
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ===========================================
+// LIVENESS E READINESS
+// ===========================================
+
+// readinessTimeout é o prazo do ping no MongoDB durante a checagem
+const readinessTimeout = 2 * time.Second
+
+// DependencyStatus descreve o estado de uma dependência externa
+type DependencyStatus struct {
+	Status    string  `json:"status"` // "up" ou "down"
+	LatencyMs float64 `json:"latency_ms"`
+	Version   string  `json:"version,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// LivenessHandler indica apenas que o processo está respondendo.
+// Não consulta dependências: se falhar, o container deve ser reiniciado.
+func (a *App) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	live := map[string]interface{}{
+		"status":    "OK",
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(live)
+}
+
+// ReadinessHandler verifica se a aplicação consegue atender requisições,
+// fazendo ping no MongoDB. Responde 503 se alguma dependência estiver fora.
+func (a *App) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	dependencies := map[string]DependencyStatus{}
+	ready := true
+
+	if a.DB != nil {
+		mongoStatus := a.checkMongo(r.Context())
+		dependencies["mongodb"] = mongoStatus
+		ready = mongoStatus.Status == "up"
+	}
+
+	status, code := "OK", http.StatusOK
+	if !ready {
+		status, code = "UNAVAILABLE", http.StatusServiceUnavailable
+	}
+
+	readiness := map[string]interface{}{
+		"status":       status,
+		"environment":  a.Config.Environment,
+		"user_store":   a.Config.UserStore,
+		"dependencies": dependencies,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(readiness)
+}
+
+// checkMongo faz ping no MongoDB e consulta a versão do servidor
+func (a *App) checkMongo(parent context.Context) DependencyStatus {
+	ctx, cancel := context.WithTimeout(parent, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := a.DB.Client().Ping(ctx, nil)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		return DependencyStatus{Status: "down", LatencyMs: latency, Error: RedactURI(err.Error())}
+	}
+
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+	// A versão é informativa: falha aqui não torna a dependência indisponível
+	a.DB.Client().Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo)
+
+	return DependencyStatus{Status: "up", LatencyMs: latency, Version: buildInfo.Version}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ===========================================
+// ÍNDICES E CONFLITOS DE CHAVE ÚNICA
+// ===========================================
+
+// userIndexes são os índices exigidos pela aplicação na coleção users.
+// São os mesmos criados por docker/mongo-init-prod.js (com os nomes padrão
+// do MongoDB), então criá-los novamente em produção não tem efeito.
+var userIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	{
+		Keys: bson.D{{Key: "created_at", Value: -1}},
+	},
+	{
+		Keys: bson.D{{Key: "name", Value: "text"}, {Key: "email", Value: "text"}},
+	},
+}
+
+// refreshTokenIndexes: busca pelo hash e expiração automática (TTL)
+var refreshTokenIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+	{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	},
+	{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	},
+}
+
+// apiKeyIndexes: busca pelo hash enviado em X-API-Key
+var apiKeyIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+// auditIndexes: o primeiro é o mesmo de docker/mongo-init-prod.js
+var auditIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: -1}},
+	},
+	{
+		Keys: bson.D{{Key: "target", Value: 1}, {Key: "timestamp", Value: -1}},
+	},
+	{
+		Keys: bson.D{{Key: "actor", Value: 1}, {Key: "timestamp", Value: -1}},
+	},
+}
+
+// collectionIndexes associa cada coleção aos seus índices
+var collectionIndexes = []struct {
+	collection string
+	indexes    []mongo.IndexModel
+}{
+	{"users", userIndexes},
+	{"refresh_tokens", refreshTokenIndexes},
+	{"api_keys", apiKeyIndexes},
+	{"audit", auditIndexes},
+}
+
+// EnsureIndexes cria os índices necessários caso ainda não existam
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, c := range collectionIndexes {
+		names, err := db.Collection(c.collection).Indexes().CreateMany(ctx, c.indexes)
+		if err != nil {
+			return fmt.Errorf("erro ao criar índices de %s: %v", c.collection, err)
+		}
+
+		slog.Info("índices garantidos", "collection", c.collection, "indexes", names)
+	}
+	return nil
+}
+
+// dupKeyPattern extrai o campo da mensagem E11000, por exemplo:
+// "E11000 duplicate key error collection: db.users index: email_1 dup key: { email: \"a@b.com\" }"
+var dupKeyPattern = regexp.MustCompile(`dup key: \{ ?"?([A-Za-z0-9_.]+)"?:`)
+
+// duplicateKeyField retorna o campo que violou o índice único, se identificável
+func duplicateKeyField(err error) string {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, we := range writeErr.WriteErrors {
+			if m := dupKeyPattern.FindStringSubmatch(we.Message); m != nil {
+				return m[1]
+			}
+		}
+	}
+
+	if m := dupKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// writeConflict responde 409 indicando o campo duplicado
+func writeConflict(w http.ResponseWriter, r *http.Request, err *DuplicateKeyError) {
+	message := "Valor já cadastrado"
+	if err.Field != "" {
+		message = fmt.Sprintf("Já existe um usuário com este %s", err.Field)
+	}
+
+	writeErrorBody(w, r, http.StatusConflict, map[string]interface{}{
+		"error": message,
+		"field": err.Field,
+	})
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// JWT (HS256 / RS256)
+// ===========================================
+
+// jwtLeeway tolera pequenas diferenças de relógio entre emissor e API
+const jwtLeeway = 30 * time.Second
+
+// Claims são os campos do token usados pela API
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+}
+
+// audience aceita "aud" como string ou lista, conforme a RFC 7519
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("aud deve ser string ou lista de strings")
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTVerifier valida assinatura e claims registradas (exp, nbf, iss, aud)
+type JWTVerifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	now        func() time.Time
+}
+
+// NewJWTVerifier cria o verificador a partir de JWT_SECRET (HS256) e/ou
+// JWT_PUBLIC_KEY / JWT_PUBLIC_KEY_FILE (RS256, PEM)
+func NewJWTVerifier(config *Config) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		issuer:   config.JWTIssuer,
+		audience: config.JWTAudience,
+		now:      time.Now,
+	}
+
+	if config.JWTSecret != "" {
+		v.hmacSecret = []byte(config.JWTSecret)
+	}
+
+	pemData := config.JWTPublicKey
+	if pemData == "" && config.JWTPublicKeyFile != "" {
+		data, err := os.ReadFile(config.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler JWT_PUBLIC_KEY_FILE: %v", err)
+		}
+		pemData = string(data)
+	}
+	if pemData != "" {
+		key, err := parseRSAPublicKey(pemData)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKey = key
+	}
+
+	if v.hmacSecret == nil && v.rsaKey == nil {
+		return nil, errors.New("autenticação habilitada sem JWT_SECRET ou JWT_PUBLIC_KEY")
+	}
+	return v, nil
+}
+
+// parseRSAPublicKey aceita PEM "PUBLIC KEY" (PKIX) ou "RSA PUBLIC KEY" (PKCS#1).
+// Quebras de linha escritas como \n (comum em arquivos .env) são convertidas.
+func parseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(data, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("JWT_PUBLIC_KEY não contém um bloco PEM válido")
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler JWT_PUBLIC_KEY: %v", err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("JWT_PUBLIC_KEY não é uma chave RSA")
+		}
+		return rsaKey, nil
+	}
+}
+
+// Verify valida o token compacto (header.payload.signature) e retorna as claims
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token malformado")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("cabeçalho do token inválido")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("assinatura do token inválida")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	// O algoritmo só é aceito se houver chave configurada para ele;
+	// isso impede "alg: none" e a troca RS256 -> HS256
+	switch header.Alg {
+	case "HS256":
+		if v.hmacSecret == nil {
+			return nil, errors.New("algoritmo HS256 não habilitado")
+		}
+		mac := hmac.New(sha256.New, v.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("assinatura do token inválida")
+		}
+	case "RS256":
+		if v.rsaKey == nil {
+			return nil, errors.New("algoritmo RS256 não habilitado")
+		}
+		digest := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("assinatura do token inválida")
+		}
+	default:
+		return nil, fmt.Errorf("algoritmo %q não suportado", header.Alg)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("payload do token inválido")
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// validateClaims verifica expiração, validade inicial, emissor e audiência
+func (v *JWTVerifier) validateClaims(c *Claims) error {
+	now := v.now()
+
+	if c.ExpiresAt == 0 {
+		return errors.New("token sem expiração (exp)")
+	}
+	if now.After(time.Unix(c.ExpiresAt, 0).Add(jwtLeeway)) {
+		return errors.New("token expirado")
+	}
+	if c.NotBefore != 0 && now.Add(jwtLeeway).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("token ainda não é válido")
+	}
+	if c.Subject == "" {
+		return errors.New("token sem subject (sub)")
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return errors.New("emissor (iss) do token inválido")
+	}
+	if v.audience != "" && !c.Audience.contains(v.audience) {
+		return errors.New("audiência (aud) do token inválida")
+	}
+	return nil
+}
+
+// ===========================================
+// EMISSÃO DE TOKENS (HS256)
+// ===========================================
+
+// TokenIssuer assina os access tokens emitidos por POST /auth/login com
+// JWT_SECRET, usando o mesmo emissor e audiência aceitos pelo JWTVerifier
+type TokenIssuer struct {
+	secret     []byte
+	issuer     string
+	audience   string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// NewTokenIssuer retorna nil quando JWT_SECRET não está configurado;
+// nesse caso a API apenas valida tokens emitidos por terceiros
+func NewTokenIssuer(config *Config) *TokenIssuer {
+	if config.JWTSecret == "" {
+		return nil
+	}
+	return &TokenIssuer{
+		secret:     []byte(config.JWTSecret),
+		issuer:     config.JWTIssuer,
+		audience:   config.JWTAudience,
+		accessTTL:  config.AccessTokenTTL,
+		refreshTTL: config.RefreshTokenTTL,
+		now:        time.Now,
+	}
+}
+
+// Issue gera um access token para o usuário
+func (t *TokenIssuer) Issue(user User) (string, error) {
+	now := t.now()
+	claims := Claims{
+		Subject:   user.ID.Hex(),
+		Issuer:    t.issuer,
+		ExpiresAt: now.Add(t.accessTTL).Unix(),
+		IssuedAt:  now.Unix(),
+		Email:     user.Email,
+		Roles:     effectiveRoles(user),
+	}
+	if t.audience != "" {
+		claims.Audience = audience{t.audience}
+	}
+	return t.sign(claims)
+}
+
+// sign serializa header e claims e assina com HMAC-SHA256
+func (t *TokenIssuer) sign(claims Claims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// decodeSegment decodifica um segmento base64url JSON do token
+func decodeSegment(segment string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
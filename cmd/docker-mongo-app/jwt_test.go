@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testJWTSecret = "test-jwt-secret-with-at-least-32-bytes"
+
+// testNow é o relógio fixo dos verificadores de teste
+var testNow = time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+
+// encodeTestSegment serializa um segmento JSON em base64url
+func encodeTestSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// hmacToken monta um token com o alg informado e assinatura HMAC-SHA256
+func hmacToken(t *testing.T, alg string, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	signed := encodeTestSegment(t, map[string]string{"alg": alg, "typ": "JWT"}) + "." + encodeTestSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// rsaToken monta um token RS256 assinado com a chave privada
+func rsaToken(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	signed := encodeTestSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeTestSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims retorna claims aceitas pelos verificadores de teste
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "user-1",
+		"iss": "test-issuer",
+		"aud": "test-api",
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+}
+
+// withClaims copia validClaims aplicando as alterações (nil remove a claim)
+func withClaims(changes map[string]interface{}) map[string]interface{} {
+	claims := validClaims()
+	for key, value := range changes {
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+	}
+	return claims
+}
+
+func newTestRSAKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// newTestVerifier cria o verificador pelo mesmo caminho da aplicação
+func newTestVerifier(t *testing.T, secret, publicKey string) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(&Config{
+		JWTSecret:    secret,
+		JWTPublicKey: publicKey,
+		JWTIssuer:    "test-issuer",
+		JWTAudience:  "test-api",
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestJWTVerifyAlgorithms(t *testing.T) {
+	key, publicPEM := newTestRSAKey(t)
+	hmacOnly := newTestVerifier(t, testJWTSecret, "")
+	rsaOnly := newTestVerifier(t, "", publicPEM)
+	claims := validClaims()
+
+	noneToken := encodeTestSegment(t, map[string]string{"alg": "none", "typ": "JWT"}) + "." + encodeTestSegment(t, claims) + "."
+
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		wantErr  string
+	}{
+		{"HS256 válido", hmacOnly, hmacToken(t, "HS256", []byte(testJWTSecret), claims), ""},
+		{"RS256 válido", rsaOnly, rsaToken(t, key, claims), ""},
+		{"alg none no HS256", hmacOnly, noneToken, `algoritmo "none" não suportado`},
+		{"alg none no RS256", rsaOnly, noneToken, `algoritmo "none" não suportado`},
+		{"HS256 com segredo errado", hmacOnly, hmacToken(t, "HS256", []byte("outro-segredo-com-pelo-menos-32-bytes"), claims), "assinatura do token inválida"},
+		// Ataque clássico: HMAC usando a chave pública RSA como segredo
+		{"HS256 com apenas chave RSA", rsaOnly, hmacToken(t, "HS256", []byte(publicPEM), claims), "algoritmo HS256 não habilitado"},
+		{"RS256 assinado com HMAC", rsaOnly, hmacToken(t, "RS256", []byte(publicPEM), claims), "assinatura do token inválida"},
+		{"RS256 com apenas segredo HMAC", hmacOnly, rsaToken(t, key, claims), "algoritmo RS256 não habilitado"},
+		{"token malformado", hmacOnly, "abc.def", "token malformado"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.verifier.Verify(tt.token)
+			expectError(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("payload adulterado", func(t *testing.T) {
+		token := hmacToken(t, "HS256", []byte(testJWTSecret), claims)
+		parts := strings.Split(token, ".")
+		parts[1] = encodeTestSegment(t, withClaims(map[string]interface{}{"roles": []string{"admin"}}))
+		_, err := hmacOnly.Verify(strings.Join(parts, "."))
+		expectError(t, err, "assinatura do token inválida")
+	})
+}
+
+func TestJWTValidateClaims(t *testing.T) {
+	v := newTestVerifier(t, testJWTSecret, "")
+	unix := func(d time.Duration) int64 { return testNow.Add(d).Unix() }
+
+	tests := []struct {
+		name    string
+		changes map[string]interface{}
+		wantErr string
+	}{
+		{"válido", nil, ""},
+		{"sem exp", map[string]interface{}{"exp": nil}, "token sem expiração (exp)"},
+		{"expirado", map[string]interface{}{"exp": unix(-time.Minute)}, "token expirado"},
+		{"expirado dentro da tolerância", map[string]interface{}{"exp": unix(-jwtLeeway + time.Second)}, ""},
+		{"expirado no limite da tolerância", map[string]interface{}{"exp": unix(-jwtLeeway - time.Second)}, "token expirado"},
+		{"nbf no futuro", map[string]interface{}{"nbf": unix(time.Minute)}, "token ainda não é válido"},
+		{"nbf dentro da tolerância", map[string]interface{}{"nbf": unix(jwtLeeway - time.Second)}, ""},
+		{"nbf além da tolerância", map[string]interface{}{"nbf": unix(jwtLeeway + time.Second)}, "token ainda não é válido"},
+		{"nbf no passado", map[string]interface{}{"nbf": unix(-time.Hour)}, ""},
+		{"sem sub", map[string]interface{}{"sub": nil}, "token sem subject (sub)"},
+		{"sub vazio", map[string]interface{}{"sub": ""}, "token sem subject (sub)"},
+		{"emissor errado", map[string]interface{}{"iss": "outro"}, "emissor (iss) do token inválido"},
+		{"aud como string", map[string]interface{}{"aud": "test-api"}, ""},
+		{"aud como lista", map[string]interface{}{"aud": []string{"outra-api", "test-api"}}, ""},
+		{"aud string errada", map[string]interface{}{"aud": "outra-api"}, "audiência (aud) do token inválida"},
+		{"aud lista sem a API", map[string]interface{}{"aud": []string{"outra-api"}}, "audiência (aud) do token inválida"},
+		{"sem aud", map[string]interface{}{"aud": nil}, "audiência (aud) do token inválida"},
+		{"aud numérico", map[string]interface{}{"aud": 42}, "payload do token inválido"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := hmacToken(t, "HS256", []byte(testJWTSecret), withClaims(tt.changes))
+			claims, err := v.Verify(token)
+			expectError(t, err, tt.wantErr)
+			if err == nil && claims.Subject != "user-1" {
+				t.Fatalf("sub = %q", claims.Subject)
+			}
+		})
+	}
+}
+
+// O token emitido pelo login precisa ser aceito pelo verificador
+func TestTokenIssuerRoundTrip(t *testing.T) {
+	config := &Config{
+		JWTSecret:      testJWTSecret,
+		JWTIssuer:      "test-issuer",
+		JWTAudience:    "test-api",
+		AccessTokenTTL: time.Minute,
+	}
+	issuer := NewTokenIssuer(config)
+	issuer.now = func() time.Time { return testNow }
+
+	user := User{ID: primitive.NewObjectID(), Email: "ana@example.com", Roles: []string{RoleAdmin}}
+	token, err := issuer.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := newTestVerifier(t, testJWTSecret, "").Verify(token)
+	if err != nil {
+		t.Fatalf("token emitido recusado: %v", err)
+	}
+	if claims.Subject != user.ID.Hex() || len(claims.Roles) != 1 || claims.Roles[0] != RoleAdmin {
+		t.Fatalf("claims inesperadas: %+v", claims)
+	}
+}
+
+func TestJWTSecretValidation(t *testing.T) {
+	tests := []struct {
+		secret  string
+		wantErr string
+	}{
+		{testJWTSecret, ""},
+		{"curto", "JWT_SECRET: muito curto (5 bytes; mínimo 32 para HS256)"},
+		{"prod_jwt_super_secret_change_me_change_me", "JWT_SECRET: valor de exemplo"},
+		{"prod_jwt_super_secret_change_me", "JWT_SECRET: muito curto"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			t.Setenv("ENV_FILE", "")
+			t.Setenv("USER_STORE", "memory")
+			t.Setenv("JWT_SECRET", tt.secret)
+			_, err := LoadConfig()
+			expectError(t, err, tt.wantErr)
+		})
+	}
+}
+
+// expectError compara o erro com o trecho esperado ("" = sem erro)
+func expectError(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Fatalf("erro inesperado: %v", err)
+	case want != "" && err == nil:
+		t.Fatalf("esperado erro %q, token aceito", want)
+	case want != "" && !strings.Contains(err.Error(), want):
+		t.Fatalf("erro = %q, esperado %q", err, want)
+	}
+}
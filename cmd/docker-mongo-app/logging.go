@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// LOGGING ESTRUTURADO (log/slog)
+// ===========================================
+
+// NewLogger cria o logger conforme o ambiente: texto em development e
+// JSON em homologation/production, no nível definido por LOG_LEVEL.
+// Com DEBUG=true cada registro inclui o arquivo/linha de origem.
+func NewLogger(config *Config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:     parseLogLevel(config.LogLevel),
+		AddSource: config.Debug,
+		// Senhas de URIs e segredos da configuração nunca chegam aos logs
+		ReplaceAttr: NewRedactor(config).ReplaceAttr,
+	}
+
+	var handler slog.Handler
+	if config.Environment == "development" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler}).With(
+		slog.String("app", config.AppName),
+		slog.String("env", config.Environment),
+	)
+}
+
+// parseLogLevel converte LOG_LEVEL (debug, info, warn, error) em slog.Level
+func parseLogLevel(value string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// fatal registra o erro e encerra o processo (equivalente a log.Fatalf)
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// ===========================================
+// CONTEXTO DA REQUISIÇÃO NOS LOGS
+// ===========================================
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	traceContextKey
+	claimsKey
+	callerKey
+	logActorKey
+)
+
+// contextHandler adiciona aos registros os dados da requisição guardados
+// no contexto, quando o log é feito com slog.*Context(ctx, ...)
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if trace, ok := TraceFromContext(ctx); ok {
+		record.AddAttrs(
+			slog.String("trace_id", trace.TraceID),
+			slog.String("span_id", trace.SpanID),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// RequestIDFromContext retorna o ID da requisição, se houver
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// ===========================================
+// MIDDLEWARE DE LOG DE REQUISIÇÕES
+// ===========================================
+
+// statusRecorder captura o status e o tamanho da resposta
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap permite que http.ResponseController acesse o writer original
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// LoggingMiddleware registra cada requisição com método, rota, status,
+// bytes e latência. 5xx são logados como erro e 4xx como aviso.
+// Deve rodar depois de RequestIDMiddleware para incluir request_id e trace_id.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(r.Context(), level, "requisição HTTP",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
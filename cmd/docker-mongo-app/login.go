@@ -0,0 +1,363 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ===========================================
+// SENHAS E LOGIN
+// ===========================================
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // limite do bcrypt, em bytes
+)
+
+// dummyPasswordHash é comparado quando o email não existe, para que o tempo
+// de resposta não revele quais emails estão cadastrados
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("senha-inexistente"), bcrypt.DefaultCost)
+	return hash
+})
+
+// TokenResponse é o corpo de resposta de /auth/login e /auth/refresh
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// decodeStringFields lê um objeto JSON cujos campos são todos textos.
+// Campos fora de allowed são rejeitados; os de required são obrigatórios.
+func decodeStringFields(w http.ResponseWriter, r *http.Request, allowed, required []string) (map[string]string, error) {
+	raw, err := decodeJSONObject(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(allowed))
+	for _, field := range allowed {
+		known[field] = true
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]string, len(raw))
+	var errs ValidationErrors
+	for _, key := range keys {
+		var value string
+		switch {
+		case !known[key]:
+			errs.add(key, "campo desconhecido")
+		case json.Unmarshal(raw[key], &value) != nil:
+			errs.add(key, "deve ser um texto")
+		default:
+			values[key] = value
+		}
+	}
+
+	for _, field := range required {
+		if values[field] == "" && !errs.has(field) {
+			errs.add(field, "campo obrigatório")
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+// validatePassword aplica as regras de tamanho da nova senha
+func validatePassword(password string, errs *ValidationErrors) {
+	switch {
+	case len(password) < minPasswordLength:
+		errs.add("new_password", "deve ter pelo menos %d caracteres", minPasswordLength)
+	case len(password) > maxPasswordLength:
+		errs.add("new_password", "deve ter no máximo %d bytes", maxPasswordLength)
+	}
+}
+
+// SetPasswordHandler define ou troca a senha de um usuário.
+// Se o usuário já tiver senha, current_password é obrigatório. Com
+// autenticação habilitada a rota aceita o próprio usuário ou quem tem
+// users:manage (a.RequireSelfOr), que redefine sem a senha atual.
+// A troca revoga todos os refresh tokens emitidos.
+func (a *App) SetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	caller, authenticated := CallerFromContext(r.Context())
+	self := !authenticated || isSelf(caller, r)
+
+	fields, err := decodeStringFields(w, r, []string{"current_password", "new_password"}, []string{"new_password"})
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	var errs ValidationErrors
+	validatePassword(fields["new_password"], &errs)
+	if len(errs) > 0 {
+		writePayloadError(w, r, errs)
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	user, err := a.Users.FindByID(ctx, id)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuário", err)
+		return
+	}
+
+	if user.PasswordHash != "" && self {
+		current := fields["current_password"]
+		if current == "" {
+			writePayloadError(w, r, ValidationErrors{{Field: "current_password", Message: "campo obrigatório"}})
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+			writeError(w, r, http.StatusForbidden, "Senha atual incorreta")
+			return
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(fields["new_password"]), bcrypt.DefaultCost)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao gerar hash da senha", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Erro interno do servidor")
+		return
+	}
+
+	if err := a.Users.SetPassword(ctx, id, string(hash)); err != nil {
+		writeRepositoryError(w, r, "Erro ao gravar senha", err)
+		return
+	}
+	if err := a.RefreshTokens.DeleteByUser(ctx, id); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao revogar refresh tokens", "error", err, "user_id", id.Hex())
+	}
+
+	a.audit(r, AuditUserPassword, id.Hex(), nil)
+	slog.InfoContext(r.Context(), "senha alterada", "user_id", id.Hex())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginHandler troca email e senha por um access token e um refresh token.
+// Depois de MAX_LOGIN_ATTEMPTS falhas seguidas a conta fica bloqueada por
+// LOGIN_LOCKOUT e a API responde 423 com Retry-After.
+func (a *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	fields, err := decodeStringFields(w, r, []string{"email", "password"}, []string{"email", "password"})
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	if a.Tokens == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Emissão de tokens não configurada (JWT_SECRET)")
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	email := strings.ToLower(strings.TrimSpace(fields["email"]))
+	user, err := a.Users.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		writeRepositoryError(w, r, "Erro ao buscar usuário", err)
+		return
+	}
+	if err != nil || user.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(fields["password"]))
+		slog.WarnContext(r.Context(), "login recusado: usuário inexistente ou sem senha")
+		writeError(w, r, http.StatusUnauthorized, "Email ou senha inválidos")
+		return
+	}
+
+	if locked(user) {
+		writeLocked(w, r, *user.LockedUntil)
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(fields["password"])) != nil {
+		a.recordLoginFailure(w, r, user)
+		return
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := a.Users.ResetLoginFailures(ctx, user.ID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao zerar falhas de login", "error", err, "user_id", user.ID.Hex())
+		}
+	}
+
+	slog.InfoContext(r.Context(), "login realizado", "user_id", user.ID.Hex())
+	a.writeTokens(w, r, user)
+}
+
+// recordLoginFailure contabiliza a senha incorreta e responde 401 ou 423
+func (a *App) recordLoginFailure(w http.ResponseWriter, r *http.Request, user User) {
+	if a.maxLoginAttempts <= 0 {
+		writeError(w, r, http.StatusUnauthorized, "Email ou senha inválidos")
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	updated, err := a.Users.RecordLoginFailure(ctx, user.ID, a.maxLoginAttempts, time.Now().Add(a.loginLockout))
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao registrar falha de login", err)
+		return
+	}
+
+	if locked(updated) {
+		slog.WarnContext(r.Context(), "conta bloqueada por excesso de tentativas",
+			"user_id", user.ID.Hex(), "until", updated.LockedUntil.Format(time.RFC3339))
+		writeLocked(w, r, *updated.LockedUntil)
+		return
+	}
+
+	slog.WarnContext(r.Context(), "login recusado: senha incorreta",
+		"user_id", user.ID.Hex(), "failed_logins", updated.FailedLogins)
+	writeError(w, r, http.StatusUnauthorized, "Email ou senha inválidos")
+}
+
+// locked indica se o usuário está bloqueado neste momento
+func locked(user User) bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// writeLocked responde 423 informando em Retry-After quando tentar de novo
+func writeLocked(w http.ResponseWriter, r *http.Request, until time.Time) {
+	seconds := int(math.Ceil(time.Until(until).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	writeError(w, r, http.StatusLocked, "Conta bloqueada temporariamente por excesso de tentativas")
+}
+
+// RefreshHandler consome o refresh token e emite um novo par de tokens.
+// Conta bloqueada responde 423 como no login; o token consumido não volta a
+// valer, e a sessão só recomeça com um novo login após o bloqueio.
+func (a *App) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	fields, err := decodeStringFields(w, r, []string{"refresh_token"}, []string{"refresh_token"})
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	if a.Tokens == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "Emissão de tokens não configurada (JWT_SECRET)")
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	token, err := a.RefreshTokens.Consume(ctx, hashRefreshToken(fields["refresh_token"]))
+	if errors.Is(err, ErrRefreshTokenInvalid) {
+		writeError(w, r, http.StatusUnauthorized, "Refresh token inválido ou expirado")
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao consumir refresh token", err)
+		return
+	}
+
+	user, err := a.Users.FindByID(ctx, token.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		writeError(w, r, http.StatusUnauthorized, "Refresh token inválido ou expirado")
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuário", err)
+		return
+	}
+
+	if locked(user) {
+		slog.WarnContext(r.Context(), "refresh recusado: conta bloqueada", "user_id", user.ID.Hex())
+		writeLocked(w, r, *user.LockedUntil)
+		return
+	}
+
+	a.writeTokens(w, r, user)
+}
+
+// LogoutHandler revoga o refresh token informado. Responde 204 mesmo se o
+// token já não existir, para não revelar quais tokens são válidos.
+func (a *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	fields, err := decodeStringFields(w, r, []string{"refresh_token"}, []string{"refresh_token"})
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	_, err = a.RefreshTokens.Consume(ctx, hashRefreshToken(fields["refresh_token"]))
+	if err != nil && !errors.Is(err, ErrRefreshTokenInvalid) {
+		writeRepositoryError(w, r, "Erro ao revogar refresh token", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeTokens emite o access token e um novo refresh token para o usuário
+func (a *App) writeTokens(w http.ResponseWriter, r *http.Request, user User) {
+	accessToken, err := a.Tokens.Issue(user)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao assinar access token", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Erro interno do servidor")
+		return
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	record := RefreshToken{
+		TokenHash: hashRefreshToken(refreshToken),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.Tokens.refreshTTL),
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.RefreshTokens.Save(ctx, record); err != nil {
+		writeRepositoryError(w, r, "Erro ao gravar refresh token", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.Tokens.accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	})
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const testPassword = "senha-correta-123"
+
+// newLoginTestApp habilita a emissão de tokens com bloqueio após 3 falhas
+func newLoginTestApp(t *testing.T) *App {
+	t.Helper()
+	return newTestApp(t, map[string]string{
+		"JWT_SECRET":         testJWTSecret,
+		"MAX_LOGIN_ATTEMPTS": "3",
+		"LOGIN_LOCKOUT":      "1m",
+	})
+}
+
+// createUserWithPassword cria o usuário e define a senha via API
+func createUserWithPassword(t *testing.T, app *App, email string) User {
+	t.Helper()
+	user := createUser(t, app, "Usuário", email, 30)
+	w := doRequest(app, http.MethodPut, "/users/"+user.ID.Hex()+"/password", `{"new_password":"`+testPassword+`"}`)
+	expectStatus(t, w, http.StatusNoContent)
+	return user
+}
+
+func login(app *App, email, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	return doRequest(app, http.MethodPost, "/auth/login", string(body))
+}
+
+// loginTokens faz login com a senha correta e retorna os tokens
+func loginTokens(t *testing.T, app *App, email string) TokenResponse {
+	t.Helper()
+	w := login(app, email, testPassword)
+	expectStatus(t, w, http.StatusOK)
+
+	var tokens TokenResponse
+	decodeBody(t, w, &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("tokens vazios: %+v", tokens)
+	}
+	return tokens
+}
+
+func refresh(app *App, token string) *httptest.ResponseRecorder {
+	return doRequest(app, http.MethodPost, "/auth/refresh", `{"refresh_token":"`+token+`"}`)
+}
+
+// failedLogins lê o contador gravado no repositório
+func failedLogins(t *testing.T, app *App, id primitive.ObjectID) int {
+	t.Helper()
+	user, err := app.Users.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.FailedLogins
+}
+
+func TestLoginLockout(t *testing.T) {
+	app := newLoginTestApp(t)
+	user := createUserWithPassword(t, app, "lock@example.com")
+
+	expectStatus(t, login(app, "lock@example.com", "errada-1"), http.StatusUnauthorized)
+	expectStatus(t, login(app, "lock@example.com", "errada-2"), http.StatusUnauthorized)
+
+	// A terceira falha bloqueia a conta
+	w := login(app, "lock@example.com", "errada-3")
+	expectStatus(t, w, http.StatusLocked)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Fatalf("Retry-After = %q, esperado entre 1 e 60", w.Header().Get("Retry-After"))
+	}
+
+	// Durante o bloqueio nem a senha correta é aceita
+	w = login(app, "lock@example.com", testPassword)
+	expectStatus(t, w, http.StatusLocked)
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("423 sem Retry-After")
+	}
+
+	// Simula o fim do bloqueio
+	past := time.Now().Add(-time.Second)
+	repo := app.Users.(*MemoryUserRepository)
+	if err := repo.modify(context.Background(), user.ID, func(u *User) { u.LockedUntil = &past }); err != nil {
+		t.Fatal(err)
+	}
+
+	loginTokens(t, app, "lock@example.com")
+	stored, _ := app.Users.FindByID(context.Background(), user.ID)
+	if stored.FailedLogins != 0 || stored.LockedUntil != nil {
+		t.Fatalf("login não limpou o bloqueio: failed=%d locked=%v", stored.FailedLogins, stored.LockedUntil)
+	}
+}
+
+// O bloqueio também vale para sessões abertas antes dele
+func TestRefreshDuringLockout(t *testing.T) {
+	app := newLoginTestApp(t)
+	createUserWithPassword(t, app, "refresh-lock@example.com")
+	tokens := loginTokens(t, app, "refresh-lock@example.com")
+
+	for i := 1; i <= 3; i++ {
+		login(app, "refresh-lock@example.com", "errada")
+	}
+	expectStatus(t, login(app, "refresh-lock@example.com", testPassword), http.StatusLocked)
+
+	w := refresh(app, tokens.RefreshToken)
+	expectStatus(t, w, http.StatusLocked)
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("423 sem Retry-After")
+	}
+	if strings.Contains(w.Body.String(), "access_token") {
+		t.Fatalf("refresh bloqueado emitiu tokens: %s", w.Body.String())
+	}
+
+	// O token consumido não volta a valer depois do bloqueio
+	expectStatus(t, refresh(app, tokens.RefreshToken), http.StatusUnauthorized)
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	app := newLoginTestApp(t)
+	user := createUserWithPassword(t, app, "reset@example.com")
+
+	expectStatus(t, login(app, "reset@example.com", "errada"), http.StatusUnauthorized)
+	expectStatus(t, login(app, "reset@example.com", "errada"), http.StatusUnauthorized)
+	if n := failedLogins(t, app, user.ID); n != 2 {
+		t.Fatalf("failed_logins = %d, esperado 2", n)
+	}
+
+	loginTokens(t, app, "reset@example.com")
+	if n := failedLogins(t, app, user.ID); n != 0 {
+		t.Fatalf("failed_logins = %d após login, esperado 0", n)
+	}
+
+	// Com o contador zerado, mais duas falhas ainda não bloqueiam
+	expectStatus(t, login(app, "reset@example.com", "errada"), http.StatusUnauthorized)
+	expectStatus(t, login(app, "reset@example.com", "errada"), http.StatusUnauthorized)
+	loginTokens(t, app, "reset@example.com")
+}
+
+func TestLoginUnknownEmail(t *testing.T) {
+	app := newLoginTestApp(t)
+	createUser(t, app, "Sem Senha", "nopass@example.com", 30)
+
+	// Inexistente e sem senha respondem igual e nunca bloqueiam
+	for i := 0; i < 4; i++ {
+		expectStatus(t, login(app, "ghost@example.com", "qualquer"), http.StatusUnauthorized)
+		expectStatus(t, login(app, "nopass@example.com", "qualquer"), http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshTokenSingleUse(t *testing.T) {
+	app := newLoginTestApp(t)
+	createUserWithPassword(t, app, "refresh@example.com")
+	first := loginTokens(t, app, "refresh@example.com")
+
+	w := refresh(app, first.RefreshToken)
+	expectStatus(t, w, http.StatusOK)
+	var second TokenResponse
+	decodeBody(t, w, &second)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh não rotacionou o token: %+v", second)
+	}
+
+	// O token consumido não vale de novo; o novo continua válido
+	expectStatus(t, refresh(app, first.RefreshToken), http.StatusUnauthorized)
+	w = refresh(app, second.RefreshToken)
+	expectStatus(t, w, http.StatusOK)
+	var third TokenResponse
+	decodeBody(t, w, &third)
+
+	// Logout revoga o token informado
+	expectStatus(t, doRequest(app, http.MethodPost, "/auth/logout", `{"refresh_token":"`+third.RefreshToken+`"}`), http.StatusNoContent)
+	expectStatus(t, refresh(app, third.RefreshToken), http.StatusUnauthorized)
+	expectStatus(t, refresh(app, "token-inexistente"), http.StatusUnauthorized)
+}
+
+func TestPasswordChangeRevokesRefreshTokens(t *testing.T) {
+	app := newLoginTestApp(t)
+	user := createUserWithPassword(t, app, "change@example.com")
+	first := loginTokens(t, app, "change@example.com")
+	second := loginTokens(t, app, "change@example.com")
+	path := "/users/" + user.ID.Hex() + "/password"
+
+	// Com senha definida, a atual é exigida e conferida
+	expectStatus(t, doRequest(app, http.MethodPut, path, `{"new_password":"nova-senha-456"}`), http.StatusUnprocessableEntity)
+	expectStatus(t, doRequest(app, http.MethodPut, path, `{"current_password":"errada","new_password":"nova-senha-456"}`), http.StatusForbidden)
+	expectStatus(t, refresh(app, first.RefreshToken), http.StatusOK)
+
+	w := doRequest(app, http.MethodPut, path, `{"current_password":"`+testPassword+`","new_password":"nova-senha-456"}`)
+	expectStatus(t, w, http.StatusNoContent)
+
+	// Todas as sessões anteriores à troca são encerradas
+	expectStatus(t, refresh(app, second.RefreshToken), http.StatusUnauthorized)
+	expectStatus(t, login(app, "change@example.com", testPassword), http.StatusUnauthorized)
+	expectStatus(t, login(app, "change@example.com", "nova-senha-456"), http.StatusOK)
+}
+
+// testRecordLoginFailure verifica o contrato de RecordLoginFailure e
+// ResetLoginFailures, comum aos dois repositórios
+func testRecordLoginFailure(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+	user := User{Name: "Falhas", Email: "failures@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := repo.Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+	lockUntil := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+	for want := 1; want <= 2; want++ {
+		updated, err := repo.RecordLoginFailure(ctx, user.ID, 3, lockUntil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.FailedLogins != want || updated.LockedUntil != nil {
+			t.Fatalf("falha %d: failed=%d locked=%v", want, updated.FailedLogins, updated.LockedUntil)
+		}
+	}
+
+	// A falha que atinge o limite bloqueia e zera o contador
+	updated, err := repo.RecordLoginFailure(ctx, user.ID, 3, lockUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.FailedLogins != 0 || updated.LockedUntil == nil || !updated.LockedUntil.Equal(lockUntil) {
+		t.Fatalf("bloqueio: failed=%d locked=%v, esperado %v", updated.FailedLogins, updated.LockedUntil, lockUntil)
+	}
+
+	if err := repo.ResetLoginFailures(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repo.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FailedLogins != 0 || stored.LockedUntil != nil {
+		t.Fatalf("reset: failed=%d locked=%v", stored.FailedLogins, stored.LockedUntil)
+	}
+
+	if _, err := repo.RecordLoginFailure(ctx, primitive.NewObjectID(), 3, lockUntil); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("usuário inexistente: erro = %v, esperado ErrUserNotFound", err)
+	}
+}
+
+func TestMemoryRecordLoginFailure(t *testing.T) {
+	testRecordLoginFailure(t, NewMemoryUserRepository())
+}
+
+// TestMongoRecordLoginFailure roda contra um MongoDB real quando
+// MONGO_TEST_URI está definido (ex.: mongodb://localhost:27017)
+func TestMongoRecordLoginFailure(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI não definido")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	db := client.Database("app_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() { db.Drop(ctx) })
+	testRecordLoginFailure(t, NewMongoUserRepository(db))
+}
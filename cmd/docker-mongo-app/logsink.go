@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ===========================================
+// LOGS DE REQUISIÇÕES NA COLEÇÃO logs
+// ===========================================
+
+// O LogSinkMiddleware apenas enfileira um resumo de cada requisição; uma
+// goroutine grava os registros em lote (InsertMany) a cada LOG_SINK_BATCH_SIZE
+// registros ou LOG_SINK_FLUSH_INTERVAL. Com a fila cheia o registro é
+// descartado (log_sink_dropped_total) para nunca bloquear os handlers.
+
+// requestLogAction diferencia os resumos de requisição dos demais documentos
+// da coleção (ex.: "database_initialized" dos scripts de init)
+const requestLogAction = "http_request"
+
+// logTTLIndex é o nome do índice TTL em timestamp
+const logTTLIndex = "timestamp_ttl"
+
+// logSinkSkip são rotas de alta frequência que não vão para o banco
+var logSinkSkip = map[string]bool{
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+	"/metrics":      true,
+}
+
+// RequestLog é o resumo de uma requisição gravado na coleção logs
+type RequestLog struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Action      string             `json:"-" bson:"action"`
+	Environment string             `json:"environment" bson:"environment"`
+	Timestamp   time.Time          `json:"timestamp" bson:"timestamp"`
+	Method      string             `json:"method" bson:"method"`
+	Path        string             `json:"path" bson:"path"`
+	Route       string             `json:"route" bson:"route"`
+	Status      int                `json:"status" bson:"status"`
+	Bytes       int                `json:"bytes" bson:"bytes"`
+	LatencyMs   float64            `json:"latency_ms" bson:"latency_ms"`
+	RequestID   string             `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	TraceID     string             `json:"trace_id,omitempty" bson:"trace_id,omitempty"`
+	Actor       string             `json:"actor,omitempty" bson:"actor,omitempty"`
+	IP          string             `json:"ip" bson:"ip"`
+	UserAgent   string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+}
+
+// LogQuery são os filtros de GET /logs
+type LogQuery struct {
+	Method    string
+	Route     string
+	Status    *int
+	MinStatus *int
+	RequestID string
+	Actor     string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// LogStore abstrai o armazenamento dos logs de requisições
+type LogStore interface {
+	// InsertMany grava um lote de registros
+	InsertMany(ctx context.Context, entries []RequestLog) error
+
+	// List retorna os registros do filtro, dos mais recentes para os mais
+	// antigos, e o total
+	List(ctx context.Context, query LogQuery) ([]RequestLog, int64, error)
+}
+
+// ===========================================
+// SINK ASSÍNCRONO
+// ===========================================
+
+// LogSink grava os registros em lote em segundo plano
+type LogSink struct {
+	store         LogStore
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.RWMutex // protege closed e o envio em entries
+	closed  bool
+	entries chan RequestLog
+	done    chan struct{}
+}
+
+// NewLogSink cria o sink e inicia a goroutine de gravação
+func NewLogSink(store LogStore, config *Config) *LogSink {
+	s := &LogSink{
+		store:         store,
+		batchSize:     config.LogSinkBatchSize,
+		flushInterval: config.LogSinkFlushInterval,
+		entries:       make(chan RequestLog, config.LogSinkBuffer),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Enqueue adiciona o registro à fila sem bloquear; descarta se estiver cheia
+func (s *LogSink) Enqueue(entry RequestLog) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.entries <- entry:
+	default:
+		metrics.ObserveLogSinkDropped()
+	}
+}
+
+// run acumula registros e grava ao completar o lote ou a cada flushInterval
+func (s *LogSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]RequestLog, 0, s.batchSize)
+	for {
+		select {
+		case entry, ok := <-s.entries:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush grava o lote; em caso de erro os registros são descartados
+func (s *LogSink) flush(batch []RequestLog) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.store.InsertMany(ctx, batch); err != nil {
+		metrics.ObserveLogSinkFailed(len(batch))
+		slog.Error("erro ao gravar logs de requisições", "error", err, "count", len(batch))
+	}
+}
+
+// Close para de aceitar registros e aguarda a gravação dos pendentes
+func (s *LogSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.entries)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logActorSlot é preenchido pelo AuthMiddleware, que roda depois do
+// LogSinkMiddleware, com o chamador autenticado
+type logActorSlot struct {
+	subject string
+}
+
+// recordActor informa ao LogSinkMiddleware quem fez a requisição
+func recordActor(ctx context.Context, subject string) {
+	if slot, ok := ctx.Value(logActorKey).(*logActorSlot); ok {
+		slot.subject = subject
+	}
+}
+
+// LogSinkMiddleware enfileira o resumo de cada requisição no sink
+func (a *App) LogSinkMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		if logSinkSkip[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		slot := &logActorSlot{}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), logActorKey, slot)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		entry := RequestLog{
+			Action:      requestLogAction,
+			Environment: a.Config.Environment,
+			Timestamp:   start,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			Route:       route,
+			Status:      rec.status,
+			Bytes:       rec.bytes,
+			LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+			RequestID:   RequestIDFromContext(r.Context()),
+			Actor:       slot.subject,
+			IP:          clientIP(r, a.Config.TrustProxy),
+			UserAgent:   r.UserAgent(),
+		}
+		if trace, ok := TraceFromContext(r.Context()); ok {
+			entry.TraceID = trace.TraceID
+		}
+		a.LogSink.Enqueue(entry)
+	})
+}
+
+// ===========================================
+// CONSULTA (GET /logs)
+// ===========================================
+
+// parseLogQuery lê os filtros de GET /logs
+func parseLogQuery(values url.Values) (LogQuery, error) {
+	q := LogQuery{
+		Method:    strings.ToUpper(strings.TrimSpace(values.Get("method"))),
+		Route:     strings.TrimSpace(values.Get("route")),
+		RequestID: strings.TrimSpace(values.Get("request_id")),
+		Actor:     strings.TrimSpace(values.Get("actor")),
+	}
+
+	var err error
+	if q.Limit, err = intParam(values, "limit", defaultPageLimit); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(values, "offset", 0); err != nil {
+		return q, err
+	}
+	if q.Limit < 1 || q.Limit > maxPageLimit {
+		return q, fmt.Errorf("limite deve estar entre 1 e %d", maxPageLimit)
+	}
+	if q.Offset < 0 {
+		return q, fmt.Errorf("parâmetro offset não pode ser negativo")
+	}
+	if q.Status, err = optionalIntParam(values, "status"); err != nil {
+		return q, err
+	}
+	if q.MinStatus, err = optionalIntParam(values, "min_status"); err != nil {
+		return q, err
+	}
+	if q.From, err = optionalTimeParam(values, "from"); err != nil {
+		return q, err
+	}
+	if q.To, err = optionalTimeParam(values, "to"); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// Filter converte a consulta em filtro do MongoDB
+func (q LogQuery) Filter() bson.M {
+	filter := bson.M{"action": requestLogAction}
+	if q.Method != "" {
+		filter["method"] = q.Method
+	}
+	if q.Route != "" {
+		filter["route"] = q.Route
+	}
+	if q.RequestID != "" {
+		filter["request_id"] = q.RequestID
+	}
+	if q.Actor != "" {
+		filter["actor"] = q.Actor
+	}
+
+	status := bson.M{}
+	if q.Status != nil {
+		status["$eq"] = *q.Status
+	}
+	if q.MinStatus != nil {
+		status["$gte"] = *q.MinStatus
+	}
+	if len(status) > 0 {
+		filter["status"] = status
+	}
+
+	timestamp := bson.M{}
+	if q.From != nil {
+		timestamp["$gte"] = *q.From
+	}
+	if q.To != nil {
+		timestamp["$lte"] = *q.To
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+	return filter
+}
+
+// Matches aplica o mesmo filtro de Filter em memória
+func (q LogQuery) Matches(entry RequestLog) bool {
+	switch {
+	case q.Method != "" && entry.Method != q.Method:
+		return false
+	case q.Route != "" && entry.Route != q.Route:
+		return false
+	case q.RequestID != "" && entry.RequestID != q.RequestID:
+		return false
+	case q.Actor != "" && entry.Actor != q.Actor:
+		return false
+	case q.Status != nil && entry.Status != *q.Status:
+		return false
+	case q.MinStatus != nil && entry.Status < *q.MinStatus:
+		return false
+	case q.From != nil && entry.Timestamp.Before(*q.From):
+		return false
+	case q.To != nil && entry.Timestamp.After(*q.To):
+		return false
+	}
+	return true
+}
+
+// LogsHandler lista os logs de requisições com filtros e paginação
+func (a *App) LogsHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseLogQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	entries, total, err := a.Logs.List(ctx, query)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar logs", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"logs":   entries,
+		"total":  total,
+		"count":  len(entries),
+		"limit":  query.Limit,
+		"offset": query.Offset,
+	})
+}
+
+// ===========================================
+// ARMAZENAMENTO
+// ===========================================
+
+// MongoLogStore grava na coleção "logs" criada pelos scripts de init
+type MongoLogStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoLogStore cria o armazenamento sobre a coleção logs
+func NewMongoLogStore(db *mongo.Database) *MongoLogStore {
+	return &MongoLogStore{collection: db.Collection("logs")}
+}
+
+// InsertMany grava um lote sem ordem, para que um documento inválido não
+// impeça a gravação dos demais
+func (m *MongoLogStore) InsertMany(ctx context.Context, entries []RequestLog) error {
+	docs := make([]interface{}, len(entries))
+	for i := range entries {
+		docs[i] = entries[i]
+	}
+	_, err := m.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// List retorna uma página de registros e o total do filtro
+func (m *MongoLogStore) List(ctx context.Context, query LogQuery) ([]RequestLog, int64, error) {
+	filter := query.Filter()
+
+	total, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []RequestLog{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// namespaceNotFoundCode é o código do MongoDB para coleção inexistente
+const namespaceNotFoundCode = 26
+
+// EnsureLogRetention cria (ou atualiza com collMod) o índice TTL que remove
+// os logs mais antigos que retention. O usuário da aplicação precisa da ação
+// collMod na coleção logs (ver docker/mongo-init-*.js)
+func EnsureLogRetention(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	seconds := int32(retention.Seconds())
+
+	// Índice já existente: apenas ajusta o prazo, que pode mudar entre deploys
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: "logs"},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: logTTLIndex},
+			{Key: "expireAfterSeconds", Value: seconds},
+		}},
+	}).Err()
+	if err == nil {
+		slog.Info("retenção de logs atualizada", "retention", retention.String())
+		return nil
+	}
+
+	// Só cria o índice quando ele (ou a coleção) ainda não existe; falta de
+	// permissão e demais erros não podem ser mascarados pelo CreateOne
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) ||
+		!(serverErr.HasErrorCode(indexNotFoundCode) || serverErr.HasErrorCode(namespaceNotFoundCode)) {
+		return fmt.Errorf("erro ao ajustar retenção de logs: %v", err)
+	}
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "timestamp", Value: 1}},
+		Options: options.Index().SetName(logTTLIndex).SetExpireAfterSeconds(seconds),
+	}
+	if _, err := db.Collection("logs").Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("erro ao criar índice TTL de logs: %v", err)
+	}
+
+	slog.Info("retenção de logs configurada", "retention", retention.String())
+	return nil
+}
+
+// MemoryLogStore guarda os logs em memória (USER_STORE=memory) e descarta
+// os mais antigos que a retenção a cada gravação
+type MemoryLogStore struct {
+	retention time.Duration
+
+	mu      sync.RWMutex
+	entries []RequestLog
+}
+
+// NewMemoryLogStore cria um armazenamento vazio
+func NewMemoryLogStore(retention time.Duration) *MemoryLogStore {
+	return &MemoryLogStore{retention: retention}
+}
+
+// InsertMany grava um lote e remove os registros expirados
+func (m *MemoryLogStore) InsertMany(ctx context.Context, entries []RequestLog) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().Add(-m.retention)
+	kept := m.entries[:0]
+	for _, entry := range m.entries {
+		if entry.Timestamp.After(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	for _, entry := range entries {
+		entry.ID = primitive.NewObjectID()
+		kept = append(kept, entry)
+	}
+	m.entries = kept
+	return nil
+}
+
+// List retorna uma página de registros e o total do filtro
+func (m *MemoryLogStore) List(ctx context.Context, query LogQuery) ([]RequestLog, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Percorre do fim para o início: os registros são gravados em ordem
+	entries := []RequestLog{}
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if query.Matches(m.entries[i]) {
+			entries = append(entries, m.entries[i])
+		}
+	}
+
+	total := int64(len(entries))
+	if query.Offset >= len(entries) {
+		return []RequestLog{}, total, nil
+	}
+	end := min(query.Offset+query.Limit, len(entries))
+	return entries[query.Offset:end], total, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordingLogStore guarda cada lote recebido pelo sink
+type recordingLogStore struct {
+	mu      sync.Mutex
+	batches [][]RequestLog
+	err     error
+	// block, quando não nil, segura InsertMany até ser fechado
+	block   chan struct{}
+	started chan struct{}
+}
+
+func (s *recordingLogStore) InsertMany(ctx context.Context, entries []RequestLog) error {
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]RequestLog(nil), entries...))
+	return s.err
+}
+
+func (s *recordingLogStore) List(ctx context.Context, query LogQuery) ([]RequestLog, int64, error) {
+	return nil, 0, nil
+}
+
+// batchSizes devolve o tamanho de cada lote gravado até agora
+func (s *recordingLogStore) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sizes := make([]int, len(s.batches))
+	for i, batch := range s.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+// waitBatches aguarda até que n lotes tenham sido gravados
+func (s *recordingLogStore) waitBatches(t *testing.T, n int) []int {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		sizes := s.batchSizes()
+		if len(sizes) >= n {
+			return sizes
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d lotes gravados, esperado %d", len(sizes), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newTestLogSink cria um sink com a configuração informada e o fecha no fim
+func newTestLogSink(t *testing.T, store LogStore, batchSize, buffer int, interval time.Duration) *LogSink {
+	t.Helper()
+	sink := NewLogSink(store, &Config{
+		LogSinkBatchSize:     batchSize,
+		LogSinkFlushInterval: interval,
+		LogSinkBuffer:        buffer,
+	})
+	t.Cleanup(func() { sink.Close(context.Background()) })
+	return sink
+}
+
+func requestLogs(n int) []RequestLog {
+	entries := make([]RequestLog, n)
+	for i := range entries {
+		entries[i] = RequestLog{Action: requestLogAction, Path: "/users/" + strconv.Itoa(i), Timestamp: time.Now()}
+	}
+	return entries
+}
+
+func TestLogSinkBatching(t *testing.T) {
+	store := &recordingLogStore{}
+	// Intervalo longo: só o tamanho do lote e o Close disparam gravações
+	sink := newTestLogSink(t, store, 3, 100, time.Hour)
+
+	for _, entry := range requestLogs(7) {
+		sink.Enqueue(entry)
+	}
+	if sizes := store.waitBatches(t, 2); len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 3 {
+		t.Fatalf("lotes = %v, esperado [3 3]", sizes)
+	}
+
+	// Close grava o lote incompleto e descarta registros posteriores
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sink.Enqueue(requestLogs(1)[0])
+	if sizes := store.batchSizes(); len(sizes) != 3 || sizes[2] != 1 {
+		t.Fatalf("lotes = %v, esperado [3 3 1]", sizes)
+	}
+
+	// A ordem de chegada é preservada entre os lotes
+	var paths []string
+	for _, batch := range store.batches {
+		for _, entry := range batch {
+			paths = append(paths, entry.Path)
+		}
+	}
+	for i, path := range paths {
+		if path != "/users/"+strconv.Itoa(i) {
+			t.Fatalf("ordem gravada: %v", paths)
+		}
+	}
+}
+
+func TestLogSinkFlushInterval(t *testing.T) {
+	store := &recordingLogStore{}
+	sink := newTestLogSink(t, store, 100, 100, 20*time.Millisecond)
+
+	for _, entry := range requestLogs(2) {
+		sink.Enqueue(entry)
+	}
+	if sizes := store.waitBatches(t, 1); sizes[0] != 2 {
+		t.Fatalf("lotes = %v, esperado [2]", sizes)
+	}
+
+	// Sem registros novos o ticker não grava lotes vazios
+	time.Sleep(60 * time.Millisecond)
+	if sizes := store.batchSizes(); len(sizes) != 1 {
+		t.Fatalf("lotes = %v, esperado apenas [2]", sizes)
+	}
+}
+
+func TestLogSinkDropAndFailure(t *testing.T) {
+	store := &recordingLogStore{
+		err:     errors.New("mongo indisponível"),
+		block:   make(chan struct{}),
+		started: make(chan struct{}, 10),
+	}
+	sink := newTestLogSink(t, store, 1, 1, time.Hour)
+	dropped, failed := metrics.logsDropped.Load(), metrics.logsFailed.Load()
+
+	// O primeiro registro fica preso no InsertMany, o segundo ocupa a fila
+	// e o terceiro é descartado sem bloquear
+	entries := requestLogs(3)
+	sink.Enqueue(entries[0])
+	<-store.started
+	sink.Enqueue(entries[1])
+	sink.Enqueue(entries[2])
+	if got := metrics.logsDropped.Load() - dropped; got != 1 {
+		t.Fatalf("log_sink_dropped_total aumentou %d, esperado 1", got)
+	}
+
+	// Os dois lotes gravados falham e contam como perdidos
+	close(store.block)
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := metrics.logsFailed.Load() - failed; got != 2 {
+		t.Fatalf("log_sink_failed_total aumentou %d, esperado 2", got)
+	}
+}
+
+// TestMongoLogRetention roda contra um MongoDB real quando MONGO_TEST_URI
+// está definido: cria o índice TTL na coleção inexistente e depois ajusta o
+// prazo com collMod
+func TestMongoLogRetention(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI não definido")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	db := client.Database("app_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() { db.Drop(ctx) })
+
+	for _, retention := range []time.Duration{time.Hour, 2 * time.Hour} {
+		if err := EnsureLogRetention(ctx, db, retention); err != nil {
+			t.Fatalf("retenção %s: %v", retention, err)
+		}
+
+		var index struct {
+			ExpireAfterSeconds int64 `bson:"expireAfterSeconds"`
+		}
+		cursor, err := db.Collection("logs").Indexes().List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for cursor.Next(ctx) {
+			if cursor.Current.Lookup("name").StringValue() == logTTLIndex {
+				found = true
+				if err := cursor.Decode(&index); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		cursor.Close(ctx)
+		if !found || index.ExpireAfterSeconds != int64(retention.Seconds()) {
+			t.Fatalf("retenção %s: índice TTL encontrado=%v, expireAfterSeconds=%d", retention, found, index.ExpireAfterSeconds)
+		}
+	}
+}
@@ -3,14 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -20,100 +22,166 @@ import (
 // ESTRUTURAS DE DADOS
 // ===========================================
 
-// Config representa as configurações da aplicação carregadas do .env
-type Config struct {
-	Environment   string
-	AppName       string
-	AppPort       string
-	AppHost       string
-	MongoURI      string
-	MongoHost     string
-	MongoPort     string
-	MongoDatabase string
-	Debug         string
-	LogLevel      string
-	APITimeout    string
-	EnableCORS    string
-	AllowOrigins  string
-}
-
 // User representa um usuário no MongoDB
 type User struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name      string             `json:"name" bson:"name"`
 	Email     string             `json:"email" bson:"email"`
 	Age       int                `json:"age" bson:"age"`
+	Roles     []string           `json:"roles,omitempty" bson:"roles,omitempty"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+
+	// Credenciais: nunca serializadas em JSON (json:"-")
+	PasswordHash string     `json:"-" bson:"password_hash,omitempty"`
+	FailedLogins int        `json:"-" bson:"failed_logins,omitempty"`
+	LockedUntil  *time.Time `json:"-" bson:"locked_until,omitempty"`
 }
 
 // App representa nossa aplicação com suas dependências
 type App struct {
 	Config *Config
-	DB     *mongo.Database
+	DB     *mongo.Database // nil quando USER_STORE=memory
+	Users  UserRepository
+	JWT    *JWTVerifier // nil quando AUTH_ENABLED=false
 	Router *mux.Router
+
+	// Login com senha: Tokens é nil quando JWT_SECRET não está configurado
+	Tokens        *TokenIssuer
+	RefreshTokens RefreshTokenRepository
+
+	// Chaves de API para chamadas entre serviços (X-API-Key)
+	APIKeys APIKeyRepository
+
+	// Trilha de auditoria das alterações de usuários (coleção audit)
+	Audit AuditRepository
+
+	// Logs de requisições: LogSink é nil quando LOG_SINK_ENABLED=false
+	Logs    LogStore
+	LogSink *LogSink
+
+	// RateLimiter é nil quando RATE_LIMIT_ENABLED=false
+	RateLimiter *RateLimiter
+
+	// CORS é nil quando ENABLE_CORS=false; optionsRoutes guarda os métodos
+	// de cada template para os preflights
+	CORS          *CORSPolicy
+	optionsRoutes map[string][]string
+
+	// openAPISpec é o documento servido em /openapi.json, gerado em NewApp
+	openAPISpec []byte
+
+	// apiTimeout é o prazo de cada requisição nas chamadas ao banco (API_TIMEOUT)
+	apiTimeout time.Duration
+
+	// Bloqueio de login (MAX_LOGIN_ATTEMPTS, LOGIN_LOCKOUT); 0 tentativas desativa
+	maxLoginAttempts int
+	loginLockout     time.Duration
 }
 
-// ===========================================
-// CONFIGURAÇÃO E INICIALIZAÇÃO
-// ===========================================
+// NewApp cria a aplicação com o repositório de usuários informado e
+// registra as rotas. db pode ser nil ao usar MemoryUserRepository.
+func NewApp(config *Config, users UserRepository, db *mongo.Database) (*App, error) {
+	app := &App{
+		Config: config,
+		DB:     db,
+		Users:  users,
+		Router: mux.NewRouter(),
+		Tokens: NewTokenIssuer(config),
 
-// LoadConfig carrega as configurações das variáveis de ambiente
-func LoadConfig() *Config {
-	return &Config{
-		Environment:   getEnv("ENV", "development"),
-		AppName:       getEnv("APP_NAME", "go-mongo-app"),
-		AppPort:       getEnv("APP_PORT", "8080"),
-		AppHost:       getEnv("APP_HOST", "0.0.0.0"),
-		MongoURI:      getEnv("MONGO_URI", "mongodb://localhost:27017/app_development"),
-		MongoHost:     getEnv("MONGO_HOST", "localhost"),
-		MongoPort:     getEnv("MONGO_PORT", "27017"),
-		MongoDatabase: getEnv("MONGO_DATABASE", "app_development"),
-		Debug:         getEnv("DEBUG", "true"),
-		LogLevel:      getEnv("LOG_LEVEL", "debug"),
-		APITimeout:    getEnv("API_TIMEOUT", "30s"),
-		EnableCORS:    getEnv("ENABLE_CORS", "true"),
-		AllowOrigins:  getEnv("ALLOW_ORIGINS", "*"),
+		apiTimeout:       config.APITimeout,
+		maxLoginAttempts: config.MaxLoginAttempts,
+		loginLockout:     config.LoginLockout,
 	}
-}
 
-// getEnv retorna o valor da variável de ambiente ou o valor padrão
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	if db != nil {
+		app.RefreshTokens = NewMongoRefreshTokenRepository(db)
+		app.APIKeys = NewMongoAPIKeyRepository(db)
+		app.Audit = NewMongoAuditRepository(db)
+		app.Logs = NewMongoLogStore(db)
+	} else {
+		app.RefreshTokens = NewMemoryRefreshTokenRepository()
+		app.APIKeys = NewMemoryAPIKeyRepository()
+		app.Audit = NewMemoryAuditRepository()
+		app.Logs = NewMemoryLogStore(config.LogRetention)
 	}
-	return defaultValue
+
+	if config.AuthEnabled {
+		verifier, err := NewJWTVerifier(config)
+		if err != nil {
+			return nil, err
+		}
+		app.JWT = verifier
+	}
+
+	app.RateLimiter = NewRateLimiter(config)
+	app.CORS = NewCORSPolicy(config)
+
+	if config.LogSinkEnabled {
+		app.LogSink = NewLogSink(app.Logs, config)
+	}
+
+	app.SetupRoutes()
+
+	// Toda rota registrada precisa estar documentada em apiOperations, com a
+	// mesma permissão de a.Require (coberto também por openapi_test.go)
+	if err := checkOpenAPICoverage(app.Router, config); err != nil {
+		return nil, err
+	}
+	spec, err := encodeOpenAPISpec(config)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar documento OpenAPI: %v", err)
+	}
+	app.openAPISpec = spec
+	return app, nil
 }
 
-// ConnectMongoDB conecta com o MongoDB usando as configurações
-func ConnectMongoDB(config *Config) (*mongo.Database, error) {
-	// Context com timeout para conexão
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ===========================================
+// CONFIGURAÇÃO E INICIALIZAÇÃO
+// ===========================================
 
-	// Configurações de conexão
-	clientOptions := options.Client().ApplyURI(config.MongoURI)
+// NewMongoClient cria o cliente MongoDB sem aguardar a conexão.
+// O driver conecta de forma preguiçosa; o erro aqui indica URI inválida.
+func NewMongoClient(config *Config) (*mongo.Client, error) {
+	// Configurações de conexão; o monitor registra os comandos com o
+	// request_id/trace_id do contexto da requisição que os originou
+	clientOptions := options.Client().
+		ApplyURI(config.MongoURI).
+		SetMonitor(mongoCommandMonitor())
 
-	// Conectar ao MongoDB
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao conectar com MongoDB: %v", err)
+		// O erro de parsing pode conter a URI com a senha
+		return nil, fmt.Errorf("erro ao conectar com MongoDB: %s", RedactURI(err.Error()))
 	}
+	return client, nil
+}
 
-	// Verificar a conexão
-	err = client.Ping(ctx, nil)
+// ConnectMongoDB conecta com o MongoDB, tentando novamente com backoff
+// exponencial conforme MONGO_CONNECT_RETRIES e MONGO_RETRY_BACKOFF
+func ConnectMongoDB(ctx context.Context, config *Config) (*mongo.Database, error) {
+	client, err := NewMongoClient(config)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao fazer ping no MongoDB: %v", err)
+		return nil, err
 	}
 
-	// Log da conexão bem-sucedida
-	log.Printf("✅ Conectado ao MongoDB no ambiente: %s", config.Environment)
-	log.Printf("📊 Database: %s", config.MongoDatabase)
-	log.Printf("🏠 Host: %s:%s", config.MongoHost, config.MongoPort)
+	// Verificar a conexão
+	if err := WaitForMongo(ctx, client, retryPolicyFromConfig(config)); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("erro ao fazer ping no MongoDB: %s", RedactURI(err.Error()))
+	}
 
+	logMongoConnected(config)
 	return client.Database(config.MongoDatabase), nil
 }
 
+// logMongoConnected registra a conexão bem-sucedida
+func logMongoConnected(config *Config) {
+	slog.Info("conectado ao MongoDB",
+		"database", config.MongoDatabase,
+		"host", net.JoinHostPort(config.MongoHost, strconv.Itoa(config.MongoPort)))
+}
+
 // ===========================================
 // HANDLERS HTTP
 // ===========================================
@@ -132,19 +200,25 @@ func (a *App) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(health)
 }
 
-// ConfigHandler mostra as configurações da aplicação (sem senhas)
+// ConfigHandler mostra as configurações da aplicação (sem senhas). Com
+// AUTH_ENABLED=true exige config:read (apenas admin); com
+// CONFIG_ENDPOINT=disabled (padrão em produção) a rota nem é registrada.
 func (a *App) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 	config := map[string]interface{}{
 		"environment":    a.Config.Environment,
 		"app_name":       a.Config.AppName,
 		"app_port":       a.Config.AppPort,
+		"mongo_uri":      RedactURI(a.Config.MongoURI),
 		"mongo_host":     a.Config.MongoHost,
 		"mongo_port":     a.Config.MongoPort,
 		"mongo_database": a.Config.MongoDatabase,
 		"debug":          a.Config.Debug,
 		"log_level":      a.Config.LogLevel,
-		"api_timeout":    a.Config.APITimeout,
+		"api_timeout":    a.Config.APITimeout.String(),
 		"enable_cors":    a.Config.EnableCORS,
+		"auth_enabled":   a.Config.AuthEnabled,
+		"user_store":     a.Config.UserStore,
+		"rate_limit":     a.Config.RateLimitEnabled,
 		"timestamp":      time.Now().Format(time.RFC3339),
 	}
 
@@ -154,53 +228,67 @@ func (a *App) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 // CreateUserHandler cria um novo usuário
 func (a *App) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "JSON inválido", http.StatusBadRequest)
+	payload, err := decodeUserPayload(w, r, false)
+	if err != nil {
+		writePayloadError(w, r, err)
 		return
 	}
 
+	user := User{
+		Name:  *payload.Name,
+		Email: *payload.Email,
+		Age:   *payload.Age,
+		Roles: defaultRoles,
+	}
+
 	// Adicionar timestamps
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	// Inserir no MongoDB
-	collection := a.DB.Collection("users")
-	result, err := collection.InsertOne(context.Background(), user)
-	if err != nil {
-		log.Printf("Erro ao inserir usuário: %v", err)
-		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	// Inserir no repositório
+	if err := a.Users.Create(ctx, &user); err != nil {
+		writeRepositoryError(w, r, "Erro ao inserir usuário", err)
 		return
 	}
-
-	user.ID = result.InsertedID.(primitive.ObjectID)
+	a.audit(r, AuditUserCreate, user.ID.Hex(), diffUsers(nil, &user))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
 }
 
-// GetUsersHandler lista todos os usuários
+// GetUsersHandler lista os usuários com paginação, filtros e ordenação
 func (a *App) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
-	collection := a.DB.Collection("users")
-	cursor, err := collection.Find(context.Background(), bson.D{})
+	query, err := parseUserListQuery(r.URL.Query())
 	if err != nil {
-		log.Printf("Erro ao buscar usuários: %v", err)
-		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var users []User
-	if err = cursor.All(context.Background(), &users); err != nil {
-		log.Printf("Erro ao decodificar usuários: %v", err)
-		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+	if query.CursorMode {
+		a.listUsersByCursor(w, r, query)
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	users, total, err := a.Users.List(ctx, query)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuários", err)
 		return
 	}
 
 	response := map[string]interface{}{
 		"users":       users,
-		"total":       len(users),
+		"total":       total,
+		"count":       len(users),
+		"limit":       query.Limit,
+		"offset":      query.Offset,
+		"links":       pageLinks(r, query, total),
 		"environment": a.Config.Environment,
 		"timestamp":   time.Now().Format(time.RFC3339),
 	}
@@ -209,34 +297,162 @@ func (a *App) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// ===========================================
-// MIDDLEWARE
-// ===========================================
+// UserPatch representa uma atualização parcial de usuário (PATCH).
+// Campos nil não são alterados.
+type UserPatch struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Age   *int    `json:"age"`
 
-// LoggingMiddleware faz log das requisições
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("🌐 %s %s %v", r.Method, r.URL.Path, time.Since(start))
-	})
+	// Roles só é alterado por PUT /users/{id}/roles
+	Roles *[]string `json:"-"`
 }
 
-// CORSMiddleware adiciona headers CORS se habilitado
-func (a *App) CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if a.Config.EnableCORS == "true" {
-			w.Header().Set("Access-Control-Allow-Origin", a.Config.AllowOrigins)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		}
+// apply copia os campos não-nil do patch para o usuário
+func (p UserPatch) apply(user *User, now time.Time) {
+	if p.Name != nil {
+		user.Name = *p.Name
+	}
+	if p.Email != nil {
+		user.Email = *p.Email
+	}
+	if p.Age != nil {
+		user.Age = *p.Age
+	}
+	if p.Roles != nil {
+		user.Roles = *p.Roles
+	}
+	user.UpdatedAt = now
+}
 
-		if r.Method == "OPTIONS" {
-			return
-		}
+// parseUserID extrai e valida o ObjectID da rota /users/{id}
+func parseUserID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "ID inválido")
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
 
-		next.ServeHTTP(w, r)
-	})
+// GetUserHandler busca um usuário pelo ID
+func (a *App) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	user, err := a.Users.FindByID(ctx, id)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuário", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUserHandler substitui os dados de um usuário (PUT)
+func (a *App) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	payload, err := decodeUserPayload(w, r, false)
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Substitui todos os campos editáveis; created_at é preservado
+	a.applyUserUpdate(w, r, id, payload)
+}
+
+// PatchUserHandler atualiza parcialmente um usuário (PATCH)
+func (a *App) PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	patch, err := decodeUserPayload(w, r, true)
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Apenas os campos enviados são alterados
+	a.applyUserUpdate(w, r, id, patch)
+}
+
+// applyUserUpdate aplica o patch no repositório e responde com o usuário atualizado
+func (a *App) applyUserUpdate(w http.ResponseWriter, r *http.Request, id primitive.ObjectID, patch UserPatch) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	// O repositório retorna o estado anterior da mesma escrita, para que o
+	// diff da auditoria não misture atualizações concorrentes
+	before, user, err := a.Users.Update(ctx, id, patch)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao atualizar usuário", err)
+		return
+	}
+	if changes := diffUsers(&before, &user); len(changes) > 0 {
+		a.audit(r, AuditUserUpdate, id.Hex(), changes)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUserHandler remove um usuário pelo ID
+func (a *App) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	before, err := a.Users.Delete(ctx, id)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao remover usuário", err)
+		return
+	}
+	a.audit(r, AuditUserDelete, id.Hex(), diffUsers(&before, nil))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestContext deriva o contexto da requisição com o prazo de API_TIMEOUT.
+// Se o cliente desconectar ou o prazo expirar, as operações no banco são canceladas.
+func (a *App) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), a.apiTimeout)
+}
+
+// writeRepositoryError traduz os erros do repositório em respostas HTTP
+func writeRepositoryError(w http.ResponseWriter, r *http.Request, logMessage string, err error) {
+	var dupErr *DuplicateKeyError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err):
+		slog.WarnContext(r.Context(), logMessage+": tempo limite excedido", "error", err)
+		writeError(w, r, http.StatusGatewayTimeout, "Tempo limite excedido")
+	case errors.Is(err, context.Canceled):
+		// Cliente desconectou; não há para quem responder
+		slog.InfoContext(r.Context(), logMessage+": requisição cancelada pelo cliente")
+	case errors.Is(err, ErrUserNotFound):
+		writeError(w, r, http.StatusNotFound, "Usuário não encontrado")
+	case errors.As(err, &dupErr):
+		writeConflict(w, r, dupErr)
+	default:
+		slog.ErrorContext(r.Context(), logMessage, "error", err)
+		writeError(w, r, http.StatusInternalServerError, "Erro interno do servidor")
+	}
 }
 
 // ===========================================
@@ -245,68 +461,175 @@ func (a *App) CORSMiddleware(next http.Handler) http.Handler {
 
 func (a *App) SetupRoutes() {
 	// Middleware
+	a.Router.Use(RequestIDMiddleware)
 	a.Router.Use(LoggingMiddleware)
+	a.Router.Use(metrics.MetricsMiddleware)
+	if a.LogSink != nil {
+		a.Router.Use(a.LogSinkMiddleware)
+	}
 	a.Router.Use(a.CORSMiddleware)
+	if a.RateLimiter != nil {
+		// Antes da autenticação, para barrar floods de credenciais inválidas
+		a.Router.Use(a.RateLimiter.AuthFailureMiddleware)
+	}
+	a.Router.Use(a.AuthMiddleware)
+	if a.RateLimiter != nil {
+		a.Router.Use(a.RateLimiter.Middleware)
+	}
+
+	// 404/405 não passam pelos middlewares; respondem em JSON e entram nas
+	// métricas com route="unmatched"
+	a.Router.NotFoundHandler = metrics.UnmatchedHandler(http.StatusNotFound, "Recurso não encontrado")
+	a.Router.MethodNotAllowedHandler = metrics.UnmatchedHandler(http.StatusMethodNotAllowed, "Método não permitido")
 
-	// Rotas da API
+	// Rotas da API (a.Require declara a permissão exigida com AUTH_ENABLED=true)
 	a.Router.HandleFunc("/health", a.HealthHandler).Methods("GET")
-	a.Router.HandleFunc("/config", a.ConfigHandler).Methods("GET")
-	a.Router.HandleFunc("/users", a.CreateUserHandler).Methods("POST")
-	a.Router.HandleFunc("/users", a.GetUsersHandler).Methods("GET")
+	a.Router.HandleFunc("/health/live", a.LivenessHandler).Methods("GET")
+	a.Router.HandleFunc("/health/ready", a.ReadinessHandler).Methods("GET")
+	// Desligado, /config responde 404 antes da autenticação: nem 401 nem 403
+	// revelam que a rota existe
+	if configEndpointEnabled(a.Config) {
+		a.Router.Handle("/config", a.Require(PermConfigRead, a.ConfigHandler)).Methods("GET")
+	}
+	a.Router.Handle("/metrics", a.Require(PermMetricsRead, metrics.MetricsHandler)).Methods("GET")
+	a.Router.HandleFunc("/openapi.json", a.OpenAPIHandler).Methods("GET")
+	a.Router.HandleFunc("/docs", a.DocsHandler).Methods("GET")
+	a.Router.Handle("/users", a.Require(PermUsersWrite, a.CreateUserHandler)).Methods("POST")
+	a.Router.Handle("/users", a.Require(PermUsersRead, a.GetUsersHandler)).Methods("GET")
+	// /users/search antes de /users/{id}, senão "search" seria tratado como ID
+	a.Router.Handle("/users/search", a.Require(PermUsersRead, a.SearchUsersHandler)).Methods("GET")
+	a.Router.Handle("/users/{id}", a.Require(PermUsersRead, a.GetUserHandler)).Methods("GET")
+	a.Router.Handle("/users/{id}", a.Require(PermUsersWrite, a.UpdateUserHandler)).Methods("PUT")
+	a.Router.Handle("/users/{id}", a.Require(PermUsersWrite, a.PatchUserHandler)).Methods("PATCH")
+	a.Router.Handle("/users/{id}", a.Require(PermUsersDelete, a.DeleteUserHandler)).Methods("DELETE")
+	a.Router.Handle("/users/{id}/roles", a.Require(PermUsersManage, a.SetUserRolesHandler)).Methods("PUT")
+	a.Router.Handle("/users/{id}/password", a.RequireSelfOr(PermUsersManage, a.SetPasswordHandler)).Methods("PUT")
+	a.Router.Handle("/logs", a.Require(PermLogsRead, a.LogsHandler)).Methods("GET")
+	a.Router.Handle("/audit", a.Require(PermAuditRead, a.AuditHandler)).Methods("GET")
+	a.Router.Handle("/api-keys", a.Require(PermAPIKeys, a.CreateAPIKeyHandler)).Methods("POST")
+	a.Router.Handle("/api-keys", a.Require(PermAPIKeys, a.ListAPIKeysHandler)).Methods("GET")
+	a.Router.Handle("/api-keys/{id}", a.Require(PermAPIKeys, a.RevokeAPIKeyHandler)).Methods("DELETE")
+	a.Router.HandleFunc("/auth/login", a.LoginHandler).Methods("POST")
+	a.Router.HandleFunc("/auth/refresh", a.RefreshHandler).Methods("POST")
+	a.Router.HandleFunc("/auth/logout", a.LogoutHandler).Methods("POST")
 
 	// Rota raiz
 	a.Router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		welcome := map[string]interface{}{
 			"message":     fmt.Sprintf("🚀 Bem-vindo ao %s!", a.Config.AppName),
 			"environment": a.Config.Environment,
-			"endpoints": []string{
-				"GET /health - Status da aplicação",
-				"GET /config - Configurações (sem senhas)",
-				"GET /users - Lista usuários",
-				"POST /users - Cria usuário",
-			},
-			"timestamp": time.Now().Format(time.RFC3339),
+			"docs":        "/docs",
+			"endpoints":   apiEndpoints(a.Config),
+			"timestamp":   time.Now().Format(time.RFC3339),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(welcome)
 	}).Methods("GET")
+
+	// OPTIONS em todas as rotas acima (preflight CORS)
+	a.registerOptionsRoutes()
 }
 
 // ===========================================
 // FUNÇÃO PRINCIPAL
 // ===========================================
 
-func main() {
-	// Carregar configurações
-	config := LoadConfig()
+// connectInBackground aguarda o MongoDB indefinidamente (modo degradado)
+// e garante os índices quando a conexão for estabelecida
+func connectInBackground(ctx context.Context, config *Config, db *mongo.Database) {
+	policy := retryPolicyFromConfig(config)
+	policy.MaxAttempts = 0
 
-	// Log das configurações iniciais
-	log.Printf("🚀 Iniciando %s", config.AppName)
-	log.Printf("🔧 Ambiente: %s", config.Environment)
-	log.Printf("🏠 Servidor: %s:%s", config.AppHost, config.AppPort)
-	log.Printf("🐛 Debug: %s", config.Debug)
-	log.Printf("📊 Log Level: %s", config.LogLevel)
-
-	// Conectar ao MongoDB
-	db, err := ConnectMongoDB(config)
+	if err := WaitForMongo(ctx, db.Client(), policy); err != nil {
+		slog.Error("conexão com MongoDB abandonada", "error", err)
+		return
+	}
+
+	logMongoConnected(config)
+	if err := ensureIndexes(db, config); err != nil {
+		slog.Error("falha ao criar índices", "error", err)
+	}
+}
+
+// ensureIndexes garante os índices (email único, created_at, texto) e a
+// retenção dos logs (LOG_RETENTION) em todos os ambientes
+func ensureIndexes(db *mongo.Database, config *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := EnsureIndexes(ctx, db); err != nil {
+		return err
+	}
+	return EnsureLogRetention(ctx, db, config.LogRetention)
+}
+
+func main() {
+	// Carregar configurações; todos os erros são listados de uma vez
+	config, err := LoadConfig()
 	if err != nil {
-		log.Fatalf("❌ Falha ao conectar com MongoDB: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	// Criar instância da aplicação
-	app := &App{
-		Config: config,
-		DB:     db,
-		Router: mux.NewRouter(),
+	// Logger estruturado conforme ENV, LOG_LEVEL e DEBUG
+	slog.SetDefault(NewLogger(config, os.Stdout))
+
+	// Log das configurações iniciais
+	addr := net.JoinHostPort(config.AppHost, strconv.Itoa(config.AppPort))
+	slog.Info("iniciando aplicação",
+		"server", addr,
+		"env_file", config.EnvFile,
+		"debug", config.Debug,
+		"log_level", config.LogLevel)
+
+	// Cancelado no encerramento para interromper reconexões em segundo plano
+	startupCtx, cancelStartup := context.WithCancel(context.Background())
+	defer cancelStartup()
+
+	// Criar o repositório de usuários conforme USER_STORE
+	var (
+		db    *mongo.Database
+		users UserRepository
+	)
+	switch config.UserStore {
+	case "memory":
+		slog.Info("usando repositório de usuários em memória (sem MongoDB)")
+		users = NewMemoryUserRepository()
+	case "mongo":
+		if config.StartDegraded {
+			// Modo degradado: o servidor sobe antes do MongoDB responder;
+			// /health/ready retorna 503 até a conexão ser estabelecida
+			client, err := NewMongoClient(config)
+			if err != nil {
+				fatal("falha ao conectar com MongoDB", "error", err)
+			}
+			db = client.Database(config.MongoDatabase)
+			slog.Warn("iniciando em modo degradado; conectando ao MongoDB em segundo plano")
+			go connectInBackground(startupCtx, config, db)
+		} else {
+			// Conectar ao MongoDB
+			var err error
+			db, err = ConnectMongoDB(startupCtx, config)
+			if err != nil {
+				fatal("falha ao conectar com MongoDB", "error", err)
+			}
+			if err := ensureIndexes(db, config); err != nil {
+				fatal("falha ao criar índices", "error", err)
+			}
+		}
+		users = NewMongoUserRepository(db)
 	}
 
-	// Configurar rotas
-	app.SetupRoutes()
+	// Criar instância da aplicação com as rotas configuradas
+	app, err := NewApp(config, users, db)
+	if err != nil {
+		fatal("falha ao configurar aplicação", "error", err)
+	}
 
 	// Iniciar servidor
-	addr := fmt.Sprintf("%s:%s", config.AppHost, config.AppPort)
-	log.Printf("🌐 Servidor rodando em http://%s", addr)
-	log.Printf("📝 Acesse http://%s para ver os endpoints disponíveis", addr)
+	slog.Info("servidor rodando", "url", "http://"+addr)
 
-	log.Fatal(http.ListenAndServe(addr, app.Router))
+	srv := NewHTTPServer(addr, app.Router, config)
+	if err := runServer(srv, app, config.ShutdownTimeout); err != nil {
+		fatal("erro no servidor", "error", err)
+	}
 }
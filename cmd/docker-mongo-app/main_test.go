@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Os testes exercitam a API pelo app.Router com MemoryUserRepository,
+// sem MongoDB: middlewares, roteamento e handlers reais.
+
+func TestMain(m *testing.M) {
+	// LoggingMiddleware registra toda requisição; nos testes só polui a saída
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// newTestApp monta a aplicação em memória. env sobrescreve as variáveis
+// de configuração; ENV_FILE vazio impede a leitura de arquivos .env.
+func newTestApp(t *testing.T, env map[string]string) *App {
+	t.Helper()
+
+	defaults := map[string]string{
+		"ENV":                "development",
+		"ENV_FILE":           "",
+		"USER_STORE":         "memory",
+		"LOG_SINK_ENABLED":   "false",
+		"RATE_LIMIT_ENABLED": "false",
+		"AUTH_ENABLED":       "false",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+	for key, value := range defaults {
+		t.Setenv(key, value)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	app, err := NewApp(config, NewMemoryUserRepository(), nil)
+	if err != nil {
+		t.Fatalf("NewApp: %v", err)
+	}
+	return app
+}
+
+// doRequest envia a requisição pelo router da aplicação
+func doRequest(app *App, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, r)
+	return w
+}
+
+// expectStatus falha o teste se o status não for o esperado
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, esperado %d; corpo: %s", w.Code, want, w.Body.String())
+	}
+}
+
+// decodeBody decodifica a resposta JSON em v
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("resposta não é JSON válido: %v; corpo: %s", err, w.Body.String())
+	}
+}
+
+// createUser cria um usuário via POST /users e retorna a resposta decodificada
+func createUser(t *testing.T, app *App, name, email string, age int) User {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"name": name, "email": email, "age": age})
+	w := doRequest(app, http.MethodPost, "/users", string(body))
+	expectStatus(t, w, http.StatusCreated)
+
+	var user User
+	decodeBody(t, w, &user)
+	return user
+}
+
+func TestUserCRUD(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	user := createUser(t, app, "Ana Souza", "Ana@Example.com", 30)
+	if user.ID.IsZero() || user.Email != "ana@example.com" || user.CreatedAt.IsZero() {
+		t.Fatalf("usuário criado inesperado: %+v", user)
+	}
+	path := "/users/" + user.ID.Hex()
+
+	w := doRequest(app, http.MethodGet, path, "")
+	expectStatus(t, w, http.StatusOK)
+	var got User
+	decodeBody(t, w, &got)
+	if got.ID != user.ID || got.Name != "Ana Souza" {
+		t.Fatalf("GET retornou %+v", got)
+	}
+
+	w = doRequest(app, http.MethodPut, path, `{"name":"Ana Lima","email":"ana.lima@example.com","age":31}`)
+	expectStatus(t, w, http.StatusOK)
+	decodeBody(t, w, &got)
+	if got.Name != "Ana Lima" || got.Email != "ana.lima@example.com" || got.Age != 31 {
+		t.Fatalf("PUT retornou %+v", got)
+	}
+	if !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Fatalf("PUT alterou created_at: %v -> %v", user.CreatedAt, got.CreatedAt)
+	}
+
+	w = doRequest(app, http.MethodPatch, path, `{"age":32}`)
+	expectStatus(t, w, http.StatusOK)
+	decodeBody(t, w, &got)
+	if got.Age != 32 || got.Name != "Ana Lima" {
+		t.Fatalf("PATCH retornou %+v", got)
+	}
+
+	w = doRequest(app, http.MethodGet, "/users", "")
+	expectStatus(t, w, http.StatusOK)
+	var list struct {
+		Users []User `json:"users"`
+		Total int64  `json:"total"`
+	}
+	decodeBody(t, w, &list)
+	if list.Total != 1 || len(list.Users) != 1 {
+		t.Fatalf("GET /users retornou total=%d, %d usuários", list.Total, len(list.Users))
+	}
+
+	w = doRequest(app, http.MethodDelete, path, "")
+	expectStatus(t, w, http.StatusNoContent)
+	if w.Body.Len() != 0 {
+		t.Fatalf("DELETE retornou corpo: %s", w.Body.String())
+	}
+
+	expectStatus(t, doRequest(app, http.MethodGet, path, ""), http.StatusNotFound)
+}
+
+func TestUserErrors(t *testing.T) {
+	app := newTestApp(t, nil)
+	user := createUser(t, app, "Bruno", "bruno@example.com", 40)
+	missing := "/users/000000000000000000000000"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"ID malformado no GET", http.MethodGet, "/users/abc", "", http.StatusBadRequest},
+		{"ID malformado no DELETE", http.MethodDelete, "/users/abc", "", http.StatusBadRequest},
+		{"GET inexistente", http.MethodGet, missing, "", http.StatusNotFound},
+		{"PUT inexistente", http.MethodPut, missing, `{"name":"X","email":"x@example.com","age":1}`, http.StatusNotFound},
+		{"PATCH inexistente", http.MethodPatch, missing, `{"age":1}`, http.StatusNotFound},
+		{"DELETE inexistente", http.MethodDelete, missing, "", http.StatusNotFound},
+		{"email duplicado no POST", http.MethodPost, "/users", `{"name":"Outro","email":"BRUNO@example.com","age":20}`, http.StatusConflict},
+		{"JSON malformado", http.MethodPost, "/users", `{"name":`, http.StatusBadRequest},
+		{"conteúdo extra após o objeto", http.MethodPost, "/users", `{"name":"Ana","email":"ana@ex.com","age":20}}}]`, http.StatusBadRequest},
+		{"segundo objeto após o corpo", http.MethodPost, "/users", `{"name":"Ana","email":"ana@ex.com","age":20} {}`, http.StatusBadRequest},
+		{"name nulo no PATCH", http.MethodPatch, "/users/" + user.ID.Hex(), `{"name":null}`, http.StatusUnprocessableEntity},
+		{"age nulo no PUT", http.MethodPut, "/users/" + user.ID.Hex(), `{"name":"Bruno","email":"bruno@example.com","age":null}`, http.StatusUnprocessableEntity},
+		{"campos obrigatórios", http.MethodPost, "/users", `{}`, http.StatusUnprocessableEntity},
+		{"idade fora do limite", http.MethodPatch, "/users/" + user.ID.Hex(), `{"age":200}`, http.StatusUnprocessableEntity},
+		{"campo somente leitura", http.MethodPatch, "/users/" + user.ID.Hex(), `{"id":"x"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, doRequest(app, tt.method, tt.path, tt.body), tt.want)
+		})
+	}
+
+	// Duplicado ao alterar o email de outro usuário
+	other := createUser(t, app, "Carla", "carla@example.com", 22)
+	w := doRequest(app, http.MethodPatch, "/users/"+other.ID.Hex(), `{"email":"bruno@example.com"}`)
+	expectStatus(t, w, http.StatusConflict)
+	var conflict map[string]interface{}
+	decodeBody(t, w, &conflict)
+	if conflict["field"] != "email" {
+		t.Fatalf("409 sem field=email: %v", conflict)
+	}
+
+	// null é rejeitado em vez de tratado como campo ausente
+	w = doRequest(app, http.MethodPatch, "/users/"+user.ID.Hex(), `{"email":null}`)
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+	var nullErr struct {
+		Fields []FieldError `json:"fields"`
+	}
+	decodeBody(t, w, &nullErr)
+	if len(nullErr.Fields) != 1 || nullErr.Fields[0] != (FieldError{Field: "email", Message: "não pode ser nulo"}) {
+		t.Fatalf("erro inesperado para email nulo: %+v", nullErr.Fields)
+	}
+
+	// 422 lista os campos inválidos
+	w = doRequest(app, http.MethodPost, "/users", `{"name":"","email":"x","age":-1}`)
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+	var invalid struct {
+		Fields []FieldError `json:"fields"`
+	}
+	decodeBody(t, w, &invalid)
+	if len(invalid.Fields) != 3 {
+		t.Fatalf("esperados 3 campos inválidos, obtidos %+v", invalid.Fields)
+	}
+}
+
+func TestUserCursorPagination(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	var created []User
+	for _, name := range []string{"u1", "u2", "u3", "u4", "u5", "u6", "u7"} {
+		created = append(created, createUser(t, app, name, name+"@example.com", 20))
+	}
+
+	type page struct {
+		Users      []User  `json:"users"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	var seen []string
+	path := "/users?cursor=&limit=3"
+	for pages := 0; ; pages++ {
+		if pages > len(created) {
+			t.Fatal("paginação não terminou")
+		}
+		w := doRequest(app, http.MethodGet, path, "")
+		expectStatus(t, w, http.StatusOK)
+		var p page
+		decodeBody(t, w, &p)
+		for _, u := range p.Users {
+			seen = append(seen, u.Name)
+		}
+
+		// Inserções entre páginas não deslocam as páginas seguintes
+		if pages == 0 {
+			createUser(t, app, "novo", "novo@example.com", 20)
+		}
+
+		if p.NextCursor == nil {
+			break
+		}
+		path = "/users?limit=3&cursor=" + *p.NextCursor
+	}
+
+	// Ordem created_at DESC: do mais novo para o mais antigo, sem
+	// repetições nem o usuário inserido durante a paginação
+	want := "u7,u6,u5,u4,u3,u2,u1"
+	if got := strings.Join(seen, ","); got != want {
+		t.Fatalf("páginas = %s, esperado %s", got, want)
+	}
+
+	expectStatus(t, doRequest(app, http.MethodGet, "/users?cursor=xyz", ""), http.StatusBadRequest)
+	expectStatus(t, doRequest(app, http.MethodGet, "/users?cursor=&offset=3", ""), http.StatusBadRequest)
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	disabled := newTestApp(t, map[string]string{"CONFIG_ENDPOINT": "disabled"})
+	expectStatus(t, doRequest(disabled, http.MethodGet, "/config", ""), http.StatusNotFound)
+
+	// Sem autenticação, enabled é público
+	public := newTestApp(t, map[string]string{"CONFIG_ENDPOINT": "enabled"})
+	expectStatus(t, doRequest(public, http.MethodGet, "/config", ""), http.StatusOK)
+
+	// Com autenticação, só quem tem config:read (admin)
+	app := newTestApp(t, map[string]string{
+		"CONFIG_ENDPOINT": "enabled",
+		"AUTH_ENABLED":    "true",
+		"JWT_SECRET":      testJWTSecret,
+	})
+	bearer := func(app *App, role string) string {
+		token, err := app.Tokens.Issue(User{Email: role + "@example.com", Roles: []string{role}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	expectStatus(t, doRequest(app, http.MethodGet, "/config", ""), http.StatusUnauthorized)
+	expectStatus(t, doRequest(app, http.MethodGet, "/config", "", "Authorization", bearer(app, RoleOperator)), http.StatusForbidden)
+	expectStatus(t, doRequest(app, http.MethodGet, "/config", "", "Authorization", bearer(app, RoleAdmin)), http.StatusOK)
+
+	// Desligado com autenticação: 404 para todos, sem 401/403 que revelem a
+	// rota, e fora do preflight, do OpenAPI e da rota raiz
+	hidden := newTestApp(t, map[string]string{
+		"CONFIG_ENDPOINT": "disabled",
+		"AUTH_ENABLED":    "true",
+		"JWT_SECRET":      testJWTSecret,
+	})
+	expectStatus(t, doRequest(hidden, http.MethodGet, "/config", ""), http.StatusNotFound)
+	expectStatus(t, doRequest(hidden, http.MethodGet, "/config", "", "Authorization", bearer(hidden, RoleOperator)), http.StatusNotFound)
+	expectStatus(t, doRequest(hidden, http.MethodGet, "/config", "", "Authorization", bearer(hidden, RoleAdmin)), http.StatusNotFound)
+	expectStatus(t, doRequest(hidden, http.MethodOptions, "/config", "",
+		"Origin", "http://localhost:3000", "Access-Control-Request-Method", "GET"), http.StatusNotFound)
+	for _, path := range []string{"/openapi.json", "/"} {
+		w := doRequest(hidden, http.MethodGet, path, "")
+		expectStatus(t, w, http.StatusOK)
+		if strings.Contains(w.Body.String(), "/config") {
+			t.Fatalf("%s ainda expõe /config", path)
+		}
+	}
+
+	t.Setenv("ENV_FILE", "")
+	t.Setenv("USER_STORE", "memory")
+	t.Setenv("CONFIG_ENDPOINT", "protected")
+	_, err := LoadConfig()
+	expectError(t, err, `CONFIG_ENDPOINT: "protected" inválido (use enabled ou disabled)`)
+}
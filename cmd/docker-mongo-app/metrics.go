@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ===========================================
+// MÉTRICAS NO FORMATO PROMETHEUS
+// ===========================================
+
+// Implementação mínima do formato texto do Prometheus (version 0.0.4),
+// sem dependências externas: basta apontar o scrape para GET /metrics.
+
+// defaultBuckets são os mesmos buckets padrão do client_golang (em segundos)
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram acumula observações em buckets cumulativos
+type histogram struct {
+	counts []uint64 // um contador por bucket (não cumulativo)
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(defaultBuckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range defaultBuckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// requestKey identifica uma série de http_requests_total
+type requestKey struct {
+	method, route, status string
+}
+
+// routeKey identifica uma série de http_request_duration_seconds
+type routeKey struct {
+	method, route string
+}
+
+// Metrics guarda as métricas da aplicação
+type Metrics struct {
+	mu             sync.Mutex
+	requests       map[requestKey]uint64
+	durations      map[routeKey]*histogram
+	mongoDurations map[string]*histogram
+	mongoErrors    map[string]uint64
+	rateLimited    map[routeKey]uint64
+	inFlight       atomic.Int64
+	logsDropped    atomic.Uint64
+	logsFailed     atomic.Uint64
+	startTime      time.Time
+}
+
+// NewMetrics cria um registro de métricas vazio
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:       make(map[requestKey]uint64),
+		durations:      make(map[routeKey]*histogram),
+		mongoDurations: make(map[string]*histogram),
+		mongoErrors:    make(map[string]uint64),
+		rateLimited:    make(map[routeKey]uint64),
+		startTime:      time.Now(),
+	}
+}
+
+// metrics é o registro padrão, compartilhado pelo middleware HTTP e pelo
+// monitor de comandos do MongoDB (criado antes da App)
+var metrics = NewMetrics()
+
+// knownMethods são os métodos que viram label; qualquer outro verbo enviado
+// pelo cliente é agrupado em "other" para não criar séries sem limite
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodConnect: true, http.MethodTrace: true,
+}
+
+// methodLabel normaliza o método para o label "method"
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "other"
+}
+
+// ObserveRequest registra uma requisição HTTP finalizada
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	method = methodLabel(method)
+
+	m.requests[requestKey{method, route, strconv.Itoa(status)}]++
+
+	key := routeKey{method, route}
+	h, ok := m.durations[key]
+	if !ok {
+		h = newHistogram()
+		m.durations[key] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// ObserveMongoCommand registra a duração (e a falha, se houver) de um comando
+func (m *Metrics) ObserveMongoCommand(command string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.mongoDurations[command]
+	if !ok {
+		h = newHistogram()
+		m.mongoDurations[command] = h
+	}
+	h.observe(duration.Seconds())
+
+	if failed {
+		m.mongoErrors[command]++
+	}
+}
+
+// ObserveRateLimited registra uma requisição recusada com 429
+func (m *Metrics) ObserveRateLimited(method, route string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rateLimited[routeKey{methodLabel(method), route}]++
+}
+
+// ObserveLogSinkDropped registra um log descartado com a fila do sink cheia
+func (m *Metrics) ObserveLogSinkDropped() {
+	m.logsDropped.Add(1)
+}
+
+// ObserveLogSinkFailed registra logs perdidos por erro na gravação do lote
+func (m *Metrics) ObserveLogSinkFailed(count int) {
+	m.logsFailed.Add(uint64(count))
+}
+
+// MetricsMiddleware mede contagem, latência e requisições em andamento.
+// Usa o template da rota do mux (ex.: /users/{id}) para limitar a cardinalidade.
+func (m *Metrics) MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		m.ObserveRequest(r.Method, route, rec.status, time.Since(start))
+	})
+}
+
+// UnmatchedHandler responde requisições sem rota (404) ou com método não
+// registrado (405). O mux não executa os middlewares nesses casos, então a
+// requisição é contada aqui com route="unmatched".
+func (m *Metrics) UnmatchedHandler(status int, message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		writeError(w, r, status, message)
+		m.ObserveRequest(r.Method, "unmatched", status, time.Since(start))
+	})
+}
+
+// MetricsHandler expõe as métricas no formato texto do Prometheus
+func (m *Metrics) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
+
+// WriteText escreve todas as métricas em ordem determinística
+func (m *Metrics) WriteText(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// HTTP
+	writeHeader(w, "http_requests_total", "counter", "Total de requisições HTTP por método, rota e status.")
+	reqKeys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, k := range reqKeys {
+		fmt.Fprintf(w, "http_requests_total%s %d\n",
+			labels("method", k.method, "route", k.route, "status", k.status), m.requests[k])
+	}
+
+	writeHeader(w, "http_request_duration_seconds", "histogram", "Latência das requisições HTTP em segundos.")
+	durKeys := make([]routeKey, 0, len(m.durations))
+	for k := range m.durations {
+		durKeys = append(durKeys, k)
+	}
+	sort.Slice(durKeys, func(i, j int) bool {
+		if durKeys[i].route != durKeys[j].route {
+			return durKeys[i].route < durKeys[j].route
+		}
+		return durKeys[i].method < durKeys[j].method
+	})
+	for _, k := range durKeys {
+		writeHistogram(w, "http_request_duration_seconds", m.durations[k], "method", k.method, "route", k.route)
+	}
+
+	writeHeader(w, "http_requests_in_flight", "gauge", "Requisições HTTP em andamento.")
+	fmt.Fprintf(w, "http_requests_in_flight %d\n", m.inFlight.Load())
+
+	writeHeader(w, "http_rate_limited_total", "counter", "Requisições recusadas pelo rate limit (429).")
+	limitedKeys := make([]routeKey, 0, len(m.rateLimited))
+	for k := range m.rateLimited {
+		limitedKeys = append(limitedKeys, k)
+	}
+	sort.Slice(limitedKeys, func(i, j int) bool {
+		if limitedKeys[i].route != limitedKeys[j].route {
+			return limitedKeys[i].route < limitedKeys[j].route
+		}
+		return limitedKeys[i].method < limitedKeys[j].method
+	})
+	for _, k := range limitedKeys {
+		fmt.Fprintf(w, "http_rate_limited_total%s %d\n",
+			labels("method", k.method, "route", k.route), m.rateLimited[k])
+	}
+
+	// Logs de requisições
+	writeHeader(w, "log_sink_dropped_total", "counter", "Logs de requisições descartados com a fila cheia.")
+	fmt.Fprintf(w, "log_sink_dropped_total %d\n", m.logsDropped.Load())
+
+	writeHeader(w, "log_sink_failed_total", "counter", "Logs de requisições perdidos por erro de gravação.")
+	fmt.Fprintf(w, "log_sink_failed_total %d\n", m.logsFailed.Load())
+
+	// MongoDB
+	commands := make([]string, 0, len(m.mongoDurations))
+	for c := range m.mongoDurations {
+		commands = append(commands, c)
+	}
+	sort.Strings(commands)
+
+	writeHeader(w, "mongodb_command_duration_seconds", "histogram", "Duração dos comandos MongoDB em segundos.")
+	for _, c := range commands {
+		writeHistogram(w, "mongodb_command_duration_seconds", m.mongoDurations[c], "command", c)
+	}
+
+	writeHeader(w, "mongodb_command_errors_total", "counter", "Total de comandos MongoDB que falharam.")
+	for _, c := range commands {
+		fmt.Fprintf(w, "mongodb_command_errors_total%s %d\n", labels("command", c), m.mongoErrors[c])
+	}
+
+	// Runtime Go
+	writeRuntimeMetrics(w, m.startTime)
+}
+
+// writeRuntimeMetrics escreve as estatísticas do runtime Go e do processo
+func writeRuntimeMetrics(w io.Writer, startTime time.Time) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeHeader(w, "go_info", "gauge", "Versão do Go.")
+	fmt.Fprintf(w, "go_info%s 1\n", labels("version", runtime.Version()))
+
+	writeHeader(w, "go_goroutines", "gauge", "Número de goroutines.")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+
+	writeHeader(w, "go_sched_gomaxprocs_threads", "gauge", "Valor atual de GOMAXPROCS.")
+	fmt.Fprintf(w, "go_sched_gomaxprocs_threads %d\n", runtime.GOMAXPROCS(0))
+
+	writeHeader(w, "go_memstats_alloc_bytes", "gauge", "Bytes alocados e em uso no heap.")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes %d\n", mem.Alloc)
+
+	writeHeader(w, "go_memstats_sys_bytes", "gauge", "Bytes obtidos do sistema operacional.")
+	fmt.Fprintf(w, "go_memstats_sys_bytes %d\n", mem.Sys)
+
+	writeHeader(w, "go_memstats_heap_objects", "gauge", "Objetos alocados no heap.")
+	fmt.Fprintf(w, "go_memstats_heap_objects %d\n", mem.HeapObjects)
+
+	writeHeader(w, "go_gc_cycles_total", "counter", "Ciclos de garbage collection concluídos.")
+	fmt.Fprintf(w, "go_gc_cycles_total %d\n", mem.NumGC)
+
+	writeHeader(w, "go_gc_pause_seconds_total", "counter", "Tempo total de pausa do garbage collector.")
+	fmt.Fprintf(w, "go_gc_pause_seconds_total %g\n", float64(mem.PauseTotalNs)/1e9)
+
+	writeHeader(w, "process_start_time_seconds", "gauge", "Início do processo em segundos desde a época Unix.")
+	fmt.Fprintf(w, "process_start_time_seconds %d\n", startTime.Unix())
+}
+
+// writeHeader escreve as linhas # HELP e # TYPE de uma métrica
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeHistogram escreve os buckets cumulativos, _sum e _count
+func writeHistogram(w io.Writer, name string, h *histogram, labelPairs ...string) {
+	var cumulative uint64
+	for i, upper := range defaultBuckets {
+		cumulative += h.counts[i]
+		le := strconv.FormatFloat(upper, 'g', -1, 64)
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(labelPairs, "le", le)...), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(append(labelPairs, "le", "+Inf")...), h.count)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels(labelPairs...), h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels(labelPairs...), h.count)
+}
+
+// labels formata pares chave/valor como {k="v",...}
+func labels(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapa valores de label conforme o formato texto
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := NewMetrics()
+	m.ObserveRequest("GET", "/users/{id}", 200, 30*time.Millisecond)
+	m.ObserveRequest("GET", "/users/{id}", 404, 2*time.Second)
+	m.ObserveRequest("BREW", "/users", 405, time.Millisecond)
+	m.ObserveRateLimited("PURGE", "/users")
+	m.ObserveMongoCommand("find", 4*time.Millisecond, true)
+
+	var buf bytes.Buffer
+	m.WriteText(&buf)
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/users/{id}",status="200"} 1`,
+		`http_requests_total{method="GET",route="/users/{id}",status="404"} 1`,
+		`http_requests_total{method="other",route="/users",status="405"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="0.025"} 0`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="0.05"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="2.5"} 2`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="+Inf"} 2`,
+		`http_request_duration_seconds_sum{method="GET",route="/users/{id}"} 2.03`,
+		`http_request_duration_seconds_count{method="GET",route="/users/{id}"} 2`,
+		`http_rate_limited_total{method="other",route="/users"} 1`,
+		`mongodb_command_duration_seconds_bucket{command="find",le="0.005"} 1`,
+		`mongodb_command_errors_total{command="find"} 1`,
+		"http_requests_in_flight 0",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("linha ausente: %s", line)
+		}
+	}
+	if strings.Contains(out, "BREW") || strings.Contains(out, "PURGE") {
+		t.Error("método arbitrário virou label")
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	got := labels("route", `/a"b\c`+"\n")
+	if want := `{route="/a\"b\\c\n"}`; got != want {
+		t.Fatalf("labels = %s, esperado %s", got, want)
+	}
+}
+
+// 404 e 405 não passam pelo middleware e são contados como unmatched
+func TestMetricsUnmatchedRoutes(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	w := doRequest(app, http.MethodGet, "/nao-existe", "")
+	expectStatus(t, w, http.StatusNotFound)
+	var body map[string]interface{}
+	decodeBody(t, w, &body)
+	if body["error"] != "Recurso não encontrado" {
+		t.Fatalf("corpo do 404: %v", body)
+	}
+	expectStatus(t, doRequest(app, "BREW", "/users", ""), http.StatusMethodNotAllowed)
+	expectStatus(t, doRequest(app, http.MethodGet, "/health", ""), http.StatusOK)
+
+	w = doRequest(app, http.MethodGet, "/metrics", "")
+	expectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	out := w.Body.String()
+	for _, series := range []string{
+		`http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`http_requests_total{method="other",route="unmatched",status="405"}`,
+		`http_requests_total{method="GET",route="/health",status="200"}`,
+	} {
+		if !strings.Contains(out, series+" ") {
+			t.Errorf("série ausente: %s", series)
+		}
+	}
+	if strings.Contains(out, `"/nao-existe"`) || strings.Contains(out, "BREW") {
+		t.Error("path ou método do cliente virou label")
+	}
+}
@@ -0,0 +1,638 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ===========================================
+// DOCUMENTAÇÃO OPENAPI 3
+// ===========================================
+
+// apiOperations é a fonte única da documentação: gera /openapi.json e a
+// lista de endpoints da rota raiz. NewApp falha se alguma rota registrada
+// no mux não estiver aqui (ou o contrário), então incluir uma rota nova em
+// SetupRoutes exige documentá-la.
+
+// apiOperation descreve uma rota da API
+type apiOperation struct {
+	Method     string
+	Path       string
+	Tag        string
+	Summary    string
+	Permission Permission // permissão exigida por a.Require; vazio = nenhuma
+	Self       bool       // o próprio usuário ({id}) dispensa a permissão (a.RequireSelfOr)
+	Query      []string   // parâmetros de components.parameters
+	Body       string     // schema do corpo da requisição
+	Status     int        // status de sucesso (padrão 200)
+	Schema     string     // schema da resposta de sucesso; vazio = sem corpo
+	Content    string     // content type da resposta (padrão application/json)
+	Errors     []int      // respostas de erro além de 401, 403 e 429
+
+	// Enabled indica se a rota existe com a configuração; nil = sempre.
+	// Rotas desligadas não são registradas nem documentadas
+	Enabled func(config *Config) bool
+}
+
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/", Tag: "status", Summary: "Boas-vindas e lista de endpoints", Schema: "Welcome"},
+	{Method: "GET", Path: "/health", Tag: "status", Summary: "Status da aplicação", Schema: "Health"},
+	{Method: "GET", Path: "/health/live", Tag: "status", Summary: "Liveness (processo respondendo)", Schema: "Health"},
+	{Method: "GET", Path: "/health/ready", Tag: "status", Summary: "Readiness (ping no MongoDB, 503 se indisponível)",
+		Schema: "Readiness", Errors: []int{503}},
+	{Method: "GET", Path: "/config", Tag: "status", Summary: "Configurações (sem senhas; desligado em produção)",
+		Permission: PermConfigRead, Schema: "Config", Enabled: configEndpointEnabled},
+	{Method: "GET", Path: "/metrics", Tag: "observabilidade", Summary: "Métricas no formato Prometheus",
+		Permission: PermMetricsRead, Content: "text/plain"},
+	{Method: "GET", Path: "/openapi.json", Tag: "documentação", Summary: "Este documento OpenAPI 3", Schema: "OpenAPI"},
+	{Method: "GET", Path: "/docs", Tag: "documentação", Summary: "Documentação interativa", Content: "text/html"},
+
+	{Method: "GET", Path: "/users", Tag: "usuários", Summary: "Lista usuários com paginação, filtros e ordenação",
+		Permission: PermUsersRead, Query: []string{"limit", "offset", "page", "page_size", "sort", "name", "email", "min_age", "max_age", "created_after", "created_before", "cursor"},
+		Schema: "UserList", Errors: []int{400, 504}},
+	{Method: "POST", Path: "/users", Tag: "usuários", Summary: "Cria usuário",
+		Permission: PermUsersWrite, Body: "UserInput", Status: 201, Schema: "User", Errors: []int{400, 409, 413, 422}},
+	{Method: "GET", Path: "/users/search", Tag: "usuários", Summary: "Busca textual por nome ou email (relevância e destaques)",
+		Permission: PermUsersRead, Query: []string{"q", "limit"}, Schema: "UserSearchResponse", Errors: []int{400}},
+	{Method: "GET", Path: "/users/{id}", Tag: "usuários", Summary: "Busca usuário",
+		Permission: PermUsersRead, Schema: "User", Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/users/{id}", Tag: "usuários", Summary: "Substitui usuário",
+		Permission: PermUsersWrite, Body: "UserInput", Schema: "User", Errors: []int{400, 404, 409, 413, 422}},
+	{Method: "PATCH", Path: "/users/{id}", Tag: "usuários", Summary: "Atualiza parcialmente usuário",
+		Permission: PermUsersWrite, Body: "UserPatch", Schema: "User", Errors: []int{400, 404, 409, 413, 422}},
+	{Method: "DELETE", Path: "/users/{id}", Tag: "usuários", Summary: "Remove usuário",
+		Permission: PermUsersDelete, Status: 204, Errors: []int{400, 404}},
+	{Method: "PUT", Path: "/users/{id}/roles", Tag: "usuários", Summary: "Define os papéis (viewer, operator, admin)",
+		Permission: PermUsersManage, Body: "RolesInput", Schema: "User", Errors: []int{400, 404, 422}},
+	{Method: "PUT", Path: "/users/{id}/password", Tag: "autenticação", Summary: "Define ou troca a senha (o próprio usuário ou users:manage)",
+		Permission: PermUsersManage, Self: true, Body: "PasswordInput", Status: 204, Errors: []int{400, 404, 422}},
+
+	{Method: "GET", Path: "/logs", Tag: "observabilidade", Summary: "Logs de requisições",
+		Permission: PermLogsRead, Query: []string{"method", "route", "status", "min_status", "request_id", "actor", "from", "to", "limit", "offset"},
+		Schema: "LogPage", Errors: []int{400}},
+	{Method: "GET", Path: "/audit", Tag: "observabilidade", Summary: "Trilha de auditoria",
+		Permission: PermAuditRead, Query: []string{"action", "actor", "target", "from", "to", "limit", "offset"},
+		Schema: "AuditPage", Errors: []int{400}},
+
+	{Method: "POST", Path: "/api-keys", Tag: "chaves de API", Summary: "Cria chave de API (o valor só aparece nesta resposta)",
+		Permission: PermAPIKeys, Body: "APIKeyInput", Status: 201, Schema: "APIKeyCreated", Errors: []int{400, 422}},
+	{Method: "GET", Path: "/api-keys", Tag: "chaves de API", Summary: "Lista chaves de API",
+		Permission: PermAPIKeys, Schema: "APIKeyList"},
+	{Method: "DELETE", Path: "/api-keys/{id}", Tag: "chaves de API", Summary: "Revoga chave de API",
+		Permission: PermAPIKeys, Status: 204, Errors: []int{400, 404}},
+
+	{Method: "POST", Path: "/auth/login", Tag: "autenticação", Summary: "Login com email e senha (access e refresh token)",
+		Body: "LoginInput", Schema: "TokenResponse", Errors: []int{400, 422, 423, 503}},
+	{Method: "POST", Path: "/auth/refresh", Tag: "autenticação", Summary: "Renova os tokens com o refresh token",
+		Body: "RefreshInput", Schema: "TokenResponse", Errors: []int{400, 422, 503}},
+	{Method: "POST", Path: "/auth/logout", Tag: "autenticação", Summary: "Revoga o refresh token",
+		Body: "RefreshInput", Status: 204, Errors: []int{400, 422}},
+}
+
+// configEndpointEnabled registra GET /config apenas com CONFIG_ENDPOINT=enabled
+func configEndpointEnabled(config *Config) bool {
+	return config.ConfigEndpoint != "disabled"
+}
+
+// enabledOperations filtra apiOperations pelas rotas ativas na configuração
+func enabledOperations(config *Config) []apiOperation {
+	ops := make([]apiOperation, 0, len(apiOperations))
+	for _, op := range apiOperations {
+		if op.Enabled == nil || op.Enabled(config) {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// apiEndpoints lista as rotas ativas no formato "MÉTODO /rota - resumo"
+func apiEndpoints(config *Config) []string {
+	ops := enabledOperations(config)
+	endpoints := make([]string, len(ops))
+	for i, op := range ops {
+		endpoints[i] = fmt.Sprintf("%s %s - %s", op.Method, op.Path, op.Summary)
+	}
+	return endpoints
+}
+
+// ===========================================
+// SCHEMAS
+// ===========================================
+
+// Tipos Go convertidos em schema por reflexão (tags json); referências a
+// eles viram $ref
+var schemaTypes = map[reflect.Type]string{
+	reflect.TypeOf(User{}):             "User",
+	reflect.TypeOf(UserSearchResult{}): "UserSearchResult",
+	reflect.TypeOf(TokenResponse{}):    "TokenResponse",
+	reflect.TypeOf(APIKey{}):           "APIKey",
+	reflect.TypeOf(AuditEntry{}):       "AuditEntry",
+	reflect.TypeOf(FieldChange{}):      "FieldChange",
+	reflect.TypeOf(RequestLog{}):       "RequestLog",
+	reflect.TypeOf(DependencyStatus{}): "DependencyStatus",
+	reflect.TypeOf(FieldError{}):       "FieldError",
+}
+
+type schema = map[string]interface{}
+
+func ref(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+func object(required []string, properties schema) schema {
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func arrayOf(items schema) schema {
+	return schema{"type": "array", "items": items}
+}
+
+var (
+	stringSchema   = schema{"type": "string"}
+	integerSchema  = schema{"type": "integer"}
+	dateTimeSchema = schema{"type": "string", "format": "date-time"}
+	objectIDSchema = schema{"type": "string", "pattern": "^[0-9a-f]{24}$", "example": "65a4f1c2e13b2a0012345678"}
+)
+
+// typeSchema converte um tipo Go no schema correspondente
+func typeSchema(t reflect.Type) schema {
+	if name, ok := schemaTypes[t]; ok {
+		return ref(name)
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return dateTimeSchema
+	case reflect.TypeOf(primitive.ObjectID{}):
+		return objectIDSchema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schema{}
+		for k, v := range typeSchema(t.Elem()) {
+			s[k] = v
+		}
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return stringSchema
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return integerSchema
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return arrayOf(typeSchema(t.Elem()))
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return schema{} // interface{}: qualquer valor
+}
+
+// structSchema monta o objeto a partir das tags json: campos com "-" são
+// omitidos e os sem omitempty são obrigatórios
+func structSchema(t reflect.Type) schema {
+	properties := schema{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return object(required, properties)
+}
+
+// errorSchema é o envelope de writeError com campos extras
+func errorSchema(extra schema, required ...string) schema {
+	properties := schema{
+		"error":      stringSchema,
+		"request_id": schema{"type": "string", "description": "ID da requisição (X-Request-ID) para buscar nos logs"},
+	}
+	for k, v := range extra {
+		properties[k] = v
+	}
+	return object(append([]string{"error"}, required...), properties)
+}
+
+// pageSchema é o envelope das listagens com limit/offset
+func pageSchema(field, item string) schema {
+	return object([]string{field, "total", "count", "limit", "offset"}, schema{
+		field:    arrayOf(ref(item)),
+		"total":  integerSchema,
+		"count":  integerSchema,
+		"limit":  integerSchema,
+		"offset": integerSchema,
+	})
+}
+
+// componentSchemas retorna todos os schemas de components.schemas
+func componentSchemas() schema {
+	schemas := schema{}
+	for t, name := range schemaTypes {
+		schemas[name] = structSchema(t)
+	}
+
+	roles := arrayOf(schema{"type": "string", "enum": sortedKeys(rolePermissions)})
+	permissions := make([]string, 0, len(knownPermissions))
+	for perm := range knownPermissions {
+		permissions = append(permissions, string(perm))
+	}
+	sort.Strings(permissions)
+
+	name := schema{"type": "string", "minLength": 1, "maxLength": maxNameLength}
+	email := schema{"type": "string", "format": "email"}
+	age := schema{"type": "integer", "minimum": minUserAge, "maximum": maxUserAge}
+	password := schema{"type": "string", "format": "password", "minLength": minPasswordLength, "maxLength": maxPasswordLength}
+
+	schemas["UserInput"] = object([]string{"name", "email", "age"}, schema{"name": name, "email": email, "age": age})
+	schemas["UserPatch"] = object(nil, schema{"name": name, "email": email, "age": age})
+	schemas["RolesInput"] = object([]string{"roles"}, schema{"roles": roles})
+	schemas["PasswordInput"] = object([]string{"new_password"}, schema{
+		"current_password": schema{"type": "string", "format": "password", "description": "Obrigatória se o usuário já tiver senha, exceto para users:manage"},
+		"new_password":     password,
+	})
+	schemas["LoginInput"] = object([]string{"email", "password"}, schema{"email": email, "password": schema{"type": "string", "format": "password"}})
+	schemas["RefreshInput"] = object([]string{"refresh_token"}, schema{"refresh_token": stringSchema})
+	schemas["APIKeyInput"] = object([]string{"name", "scopes"}, schema{
+		"name":       schema{"type": "string", "minLength": 1},
+		"scopes":     arrayOf(schema{"type": "string", "enum": permissions}),
+		"expires_at": dateTimeSchema,
+	})
+	schemas["APIKeyCreated"] = schema{"allOf": []schema{
+		ref("APIKey"),
+		object([]string{"key"}, schema{"key": schema{"type": "string", "description": "Valor da chave; não é exibido novamente"}}),
+	}}
+	schemas["APIKeyList"] = object([]string{"api_keys", "count"}, schema{"api_keys": arrayOf(ref("APIKey")), "count": integerSchema})
+
+	schemas["UserList"] = object([]string{"users", "count", "limit", "links"}, schema{
+		"users":       arrayOf(ref("User")),
+		"total":       schema{"type": "integer", "description": "Ausente no modo cursor"},
+		"count":       integerSchema,
+		"limit":       integerSchema,
+		"offset":      schema{"type": "integer", "description": "Ausente no modo cursor"},
+		"next_cursor": schema{"type": "string", "nullable": true, "description": "Presente no modo cursor"},
+		"links": object(nil, schema{
+			"next": schema{"type": "string", "nullable": true},
+			"prev": schema{"type": "string", "nullable": true},
+		}),
+		"environment": stringSchema,
+		"timestamp":   dateTimeSchema,
+	})
+	schemas["UserSearchResponse"] = object([]string{"query", "mode", "results", "count"}, schema{
+		"query":     stringSchema,
+		"mode":      schema{"type": "string", "enum": []string{SearchModeText, SearchModeRegex}},
+		"results":   arrayOf(ref("UserSearchResult")),
+		"count":     integerSchema,
+		"timestamp": dateTimeSchema,
+	})
+	schemas["LogPage"] = pageSchema("logs", "RequestLog")
+	schemas["AuditPage"] = pageSchema("entries", "AuditEntry")
+
+	schemas["Health"] = object([]string{"status", "timestamp"}, schema{
+		"status":      stringSchema,
+		"environment": stringSchema,
+		"app_name":    stringSchema,
+		"database":    stringSchema,
+		"timestamp":   dateTimeSchema,
+	})
+	schemas["Readiness"] = object([]string{"status", "dependencies"}, schema{
+		"status":       schema{"type": "string", "enum": []string{"OK", "UNAVAILABLE"}},
+		"environment":  stringSchema,
+		"user_store":   stringSchema,
+		"dependencies": schema{"type": "object", "additionalProperties": ref("DependencyStatus")},
+		"timestamp":    dateTimeSchema,
+	})
+	schemas["Config"] = schema{"type": "object", "additionalProperties": true}
+	schemas["Welcome"] = object([]string{"message", "endpoints"}, schema{
+		"message":     stringSchema,
+		"environment": stringSchema,
+		"docs":        stringSchema,
+		"endpoints":   arrayOf(stringSchema),
+		"timestamp":   dateTimeSchema,
+	})
+	schemas["OpenAPI"] = schema{"type": "object", "description": "Documento OpenAPI 3.0"}
+
+	schemas["Error"] = errorSchema(nil)
+	schemas["ValidationError"] = errorSchema(schema{"fields": arrayOf(ref("FieldError"))}, "fields")
+	schemas["ForbiddenError"] = errorSchema(schema{"reason": stringSchema, "permission": stringSchema}, "reason", "permission")
+	schemas["ConflictError"] = errorSchema(schema{"field": stringSchema}, "field")
+	return schemas
+}
+
+// sortedKeys retorna as chaves do mapa em ordem alfabética
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ===========================================
+// PARÂMETROS E RESPOSTAS
+// ===========================================
+
+// queryParameters são os parâmetros de query reutilizados pelas operações
+var queryParameters = map[string]schema{
+	"limit":          {"description": fmt.Sprintf("Itens por página (1 a %d)", maxPageLimit), "schema": schema{"type": "integer", "minimum": 1, "maximum": maxPageLimit, "default": defaultPageLimit}},
+	"offset":         {"description": "Itens a pular", "schema": schema{"type": "integer", "minimum": 0, "default": 0}},
+	"page":           {"description": "Página (alternativa a offset, começa em 1)", "schema": schema{"type": "integer", "minimum": 1}},
+	"page_size":      {"description": "Itens por página com page", "schema": schema{"type": "integer", "minimum": 1, "maximum": maxPageLimit}},
+	"sort":           {"description": "Campos separados por vírgula; prefixo - para ordem decrescente (ex.: -created_at,name)", "schema": stringSchema},
+	"name":           {"description": "Filtra por nome (contém, sem diferenciar maiúsculas)", "schema": stringSchema},
+	"email":          {"description": "Filtra por email (contém)", "schema": stringSchema},
+	"min_age":        {"description": "Idade mínima", "schema": integerSchema},
+	"max_age":        {"description": "Idade máxima", "schema": integerSchema},
+	"created_after":  {"description": "Criados depois da data (RFC3339 ou YYYY-MM-DD)", "schema": stringSchema},
+	"created_before": {"description": "Criados antes da data (RFC3339 ou YYYY-MM-DD)", "schema": stringSchema},
+	"cursor":         {"description": "Paginação por cursor; vazio inicia a primeira página (não aceita offset, page e sort)", "schema": stringSchema, "allowEmptyValue": true},
+	"q":              {"description": fmt.Sprintf("Texto buscado no nome e no email (até %d caracteres)", maxSearchLength), "required": true, "schema": schema{"type": "string", "maxLength": maxSearchLength}},
+	"action":         {"description": "Ação (user.create, user.update, user.delete, user.password)", "schema": stringSchema},
+	"actor":          {"description": "Quem executou (subject do token ou ID da chave de API)", "schema": stringSchema},
+	"target":         {"description": "ID do usuário afetado", "schema": stringSchema},
+	"from":           {"description": "A partir da data (RFC3339 ou YYYY-MM-DD)", "schema": stringSchema},
+	"to":             {"description": "Até a data (RFC3339 ou YYYY-MM-DD)", "schema": stringSchema},
+	"method":         {"description": "Método HTTP", "schema": stringSchema},
+	"route":          {"description": "Template da rota (ex.: /users/{id})", "schema": stringSchema},
+	"status":         {"description": "Status HTTP exato", "schema": integerSchema},
+	"min_status":     {"description": "Status HTTP mínimo (ex.: 500)", "schema": integerSchema},
+	"request_id":     {"description": "ID da requisição", "schema": stringSchema},
+}
+
+// errorResponses descreve cada status de erro e o schema do corpo
+var errorResponses = map[int]struct {
+	Description string
+	Schema      string
+}{
+	400: {"Requisição inválida (parâmetro, ID ou JSON)", "Error"},
+	401: {"Token ou chave de API ausente ou inválido", "Error"},
+	403: {"Permissão negada", "ForbiddenError"},
+	404: {"Recurso não encontrado", "Error"},
+	409: {"Valor já cadastrado", "ConflictError"},
+	413: {"Corpo da requisição muito grande", "Error"},
+	422: {"Dados inválidos", "ValidationError"},
+	423: {"Conta bloqueada temporariamente (Retry-After)", "Error"},
+	429: {"Limite de requisições excedido (Retry-After)", "Error"},
+	503: {"Serviço indisponível", "Error"},
+	504: {"Tempo limite excedido", "Error"},
+}
+
+// operationSpec converte a operação no objeto OpenAPI
+func operationSpec(op apiOperation) schema {
+	spec := schema{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op),
+	}
+
+	var params []schema
+	if strings.Contains(op.Path, "{id}") {
+		params = append(params, schema{"name": "id", "in": "path", "required": true, "schema": objectIDSchema})
+	}
+	for _, name := range op.Query {
+		params = append(params, schema{"$ref": "#/components/parameters/" + name})
+	}
+	if len(params) > 0 {
+		spec["parameters"] = params
+	}
+
+	if op.Body != "" {
+		spec["requestBody"] = schema{
+			"required": true,
+			"content":  schema{"application/json": schema{"schema": ref(op.Body)}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := schema{"description": http.StatusText(status)}
+	switch {
+	case op.Content != "":
+		success["content"] = schema{op.Content: schema{"schema": stringSchema}}
+	case op.Schema != "":
+		success["content"] = schema{"application/json": schema{"schema": ref(op.Schema)}}
+	}
+	responses := schema{fmt.Sprint(status): success}
+
+	errors := op.Errors
+	if !publicRoutes[op.Path] {
+		errors = append(errors, 401, 403)
+		spec["security"] = []schema{{"bearerAuth": []string{}}, {"apiKeyAuth": []string{}}}
+	} else {
+		spec["security"] = []schema{}
+	}
+	switch {
+	case op.Self:
+		spec["x-permission"] = op.Permission
+		spec["x-self-allowed"] = true
+		spec["description"] = fmt.Sprintf("Exige ser o próprio usuário ou a permissão `%s` (com AUTH_ENABLED=true).", op.Permission)
+	case op.Permission != "":
+		spec["x-permission"] = op.Permission
+		spec["description"] = fmt.Sprintf("Exige a permissão `%s` (com AUTH_ENABLED=true).", op.Permission)
+	}
+	if !rateLimitExempt[op.Path] {
+		errors = append(errors, 429)
+	}
+	for _, code := range errors {
+		responses[fmt.Sprint(code)] = schema{"$ref": fmt.Sprintf("#/components/responses/%d", code)}
+	}
+	spec["responses"] = responses
+	return spec
+}
+
+// operationID gera um identificador estável, ex.: "get_users_id_roles"
+func operationID(op apiOperation) string {
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_", ".", "_")
+	id := strings.Trim(replacer.Replace(op.Path), "_")
+	if id == "" {
+		id = "root"
+	}
+	return strings.ToLower(op.Method) + "_" + id
+}
+
+// BuildOpenAPISpec gera o documento OpenAPI 3 a partir das operações ativas
+// em apiOperations
+func BuildOpenAPISpec(config *Config) schema {
+	paths := schema{}
+	for _, op := range enabledOperations(config) {
+		item, ok := paths[op.Path].(schema)
+		if !ok {
+			item = schema{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operationSpec(op)
+	}
+
+	parameters := schema{}
+	for name, param := range queryParameters {
+		p := schema{"name": name, "in": "query"}
+		for k, v := range param {
+			p[k] = v
+		}
+		parameters[name] = p
+	}
+
+	responses := schema{}
+	for code, resp := range errorResponses {
+		r := schema{
+			"description": resp.Description,
+			"content":     schema{"application/json": schema{"schema": ref(resp.Schema)}},
+		}
+		if code == 423 || code == 429 {
+			r["headers"] = schema{"Retry-After": schema{"description": "Segundos até nova tentativa", "schema": integerSchema}}
+		}
+		responses[fmt.Sprint(code)] = r
+	}
+
+	return schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":       config.AppName,
+			"version":     "1.0.0",
+			"description": fmt.Sprintf("API de usuários (%s). Com AUTH_ENABLED=false nenhuma rota exige autenticação.", config.Environment),
+		},
+		"servers": []schema{{"url": "/"}},
+		"paths":   paths,
+		"components": schema{
+			"schemas":    componentSchemas(),
+			"parameters": parameters,
+			"responses":  responses,
+			"securitySchemes": schema{
+				"bearerAuth": schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": schema{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// accessRule descreve a regra de acesso para comparação: "users:read",
+// "self|users:manage" (RequireSelfOr) ou "" (nenhuma)
+func accessRule(perm Permission, self bool) string {
+	if self {
+		return "self|" + string(perm)
+	}
+	return string(perm)
+}
+
+// routeAccess retorna a regra declarada com Require ou RequireSelfOr
+func routeAccess(route *mux.Route) string {
+	if h, ok := route.GetHandler().(permissionHandler); ok {
+		return accessRule(h.perm, h.self)
+	}
+	return ""
+}
+
+// checkOpenAPICoverage compara as rotas registradas no mux com as operações
+// ativas em apiOperations, inclusive a permissão de a.Require, e retorna erro
+// listando as divergências
+func checkOpenAPICoverage(router *mux.Router, config *Config) error {
+	documented := make(map[string]string)
+	for _, op := range enabledOperations(config) {
+		documented[op.Method+" "+op.Path] = accessRule(op.Permission, op.Self)
+	}
+
+	var missing, mismatched []string
+	registered := make(map[string]bool)
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			missing = append(missing, "* "+tpl)
+			return nil
+		}
+		for _, method := range methods {
+			// OPTIONS é registrado automaticamente para o preflight CORS
+			if method == http.MethodOptions {
+				continue
+			}
+			key := method + " " + tpl
+			registered[key] = true
+			rule, ok := documented[key]
+			switch {
+			case !ok:
+				missing = append(missing, key)
+			case rule != routeAccess(route):
+				mismatched = append(mismatched, fmt.Sprintf("%s (rota: %q, apiOperations: %q)", key, routeAccess(route), rule))
+			}
+		}
+		return nil
+	})
+
+	var stale []string
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("rotas sem documentação OpenAPI (apiOperations): %s", strings.Join(missing, ", "))
+	case len(stale) > 0:
+		return fmt.Errorf("rotas documentadas mas não registradas: %s", strings.Join(stale, ", "))
+	case len(mismatched) > 0:
+		return fmt.Errorf("permissão divergente entre a.Require e apiOperations: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// ===========================================
+// HANDLERS
+// ===========================================
+
+//go:embed docs.html
+var docsPage []byte
+
+// OpenAPIHandler serve o documento gerado em NewApp
+func (a *App) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(a.openAPISpec)
+}
+
+// DocsHandler serve a página de documentação, sem dependências externas
+func (a *App) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write(docsPage)
+}
+
+// encodeOpenAPISpec serializa o documento uma única vez
+func encodeOpenAPISpec(config *Config) ([]byte, error) {
+	return json.MarshalIndent(BuildOpenAPISpec(config), "", "  ")
+}
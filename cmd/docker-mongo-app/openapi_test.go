@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// registeredRoutes lista as rotas do mux (exceto OPTIONS) com a regra
+// declarada em a.Require ou a.RequireSelfOr
+func registeredRoutes(t *testing.T, router *mux.Router) map[string]string {
+	t.Helper()
+	routes := make(map[string]string)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("rota sem método: %s", tpl)
+			return nil
+		}
+		for _, method := range methods {
+			if method != http.MethodOptions {
+				routes[method+" "+tpl] = routeAccess(route)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return routes
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	for _, endpoint := range []string{"enabled", "disabled"} {
+		t.Run("CONFIG_ENDPOINT="+endpoint, func(t *testing.T) {
+			testOpenAPICoversRoutes(t, newTestApp(t, map[string]string{"CONFIG_ENDPOINT": endpoint}))
+		})
+	}
+}
+
+func testOpenAPICoversRoutes(t *testing.T, app *App) {
+	routes := registeredRoutes(t, app.Router)
+
+	documented := make(map[string]apiOperation)
+	for _, op := range enabledOperations(app.Config) {
+		key := op.Method + " " + op.Path
+		if _, dup := documented[key]; dup {
+			t.Errorf("operação duplicada em apiOperations: %s", key)
+		}
+		documented[key] = op
+	}
+
+	// Rota → spec, com a mesma permissão de a.Require
+	for key, rule := range routes {
+		op, ok := documented[key]
+		if !ok {
+			t.Errorf("rota sem documentação OpenAPI: %s", key)
+			continue
+		}
+		if want := accessRule(op.Permission, op.Self); want != rule {
+			t.Errorf("%s: apiOperations declara %q, SetupRoutes usa %q", key, want, rule)
+		}
+	}
+
+	// Spec → rota
+	for key := range documented {
+		if _, ok := routes[key]; !ok {
+			t.Errorf("operação documentada mas não registrada: %s", key)
+		}
+	}
+
+	if err := checkOpenAPICoverage(app.Router, app.Config); err != nil {
+		t.Fatalf("checkOpenAPICoverage: %v", err)
+	}
+}
+
+// As divergências precisam ser detectadas também na inicialização (NewApp)
+func TestCheckOpenAPICoverageDetectsDrift(t *testing.T) {
+	app := &App{}
+	config := &Config{ConfigEndpoint: "enabled"}
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	// newRouter registra apiOperations, aplicando change à operação alterada
+	newRouter := func(change func(op *apiOperation) bool) *mux.Router {
+		router := mux.NewRouter()
+		for _, op := range apiOperations {
+			if change != nil && !change(&op) {
+				continue
+			}
+			handler := app.Require(op.Permission, noop)
+			if op.Self {
+				handler = app.RequireSelfOr(op.Permission, noop)
+			}
+			router.Handle(op.Path, handler).Methods(op.Method)
+		}
+		return router
+	}
+
+	tests := []struct {
+		name    string
+		router  *mux.Router
+		wantErr string
+	}{
+		{"em dia", newRouter(nil), ""},
+		{"rota sem documentação", func() *mux.Router {
+			router := newRouter(nil)
+			router.HandleFunc("/nao-documentada", noop).Methods("GET")
+			return router
+		}(), "rotas sem documentação OpenAPI (apiOperations): GET /nao-documentada"},
+		{"operação não registrada", newRouter(func(op *apiOperation) bool {
+			return op.Method+" "+op.Path != "DELETE /users/{id}"
+		}), "rotas documentadas mas não registradas: DELETE /users/{id}"},
+		{"permissão divergente", newRouter(func(op *apiOperation) bool {
+			if op.Method+" "+op.Path == "DELETE /users/{id}" {
+				op.Permission = PermUsersWrite
+			}
+			return true
+		}), `DELETE /users/{id} (rota: "users:write", apiOperations: "users:delete")`},
+		{"permissão ausente na rota", newRouter(func(op *apiOperation) bool {
+			if op.Method+" "+op.Path == "GET /logs" {
+				op.Permission = ""
+			}
+			return true
+		}), `GET /logs (rota: "", apiOperations: "logs:read")`},
+		{"próprio usuário não aceito na rota", newRouter(func(op *apiOperation) bool {
+			if op.Method+" "+op.Path == "PUT /users/{id}/password" {
+				op.Self = false
+			}
+			return true
+		}), `PUT /users/{id}/password (rota: "users:manage", apiOperations: "self|users:manage")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, checkOpenAPICoverage(tt.router, config), tt.wantErr)
+		})
+	}
+
+	// Com CONFIG_ENDPOINT=disabled, /config não pode ser registrado
+	expectError(t, checkOpenAPICoverage(newRouter(nil), &Config{ConfigEndpoint: "disabled"}),
+		"rotas sem documentação OpenAPI (apiOperations): GET /config")
+}
+
+// O documento servido expõe a permissão de cada operação protegida
+func TestOpenAPISpecPermissions(t *testing.T) {
+	app := newTestApp(t, nil)
+	w := doRequest(app, http.MethodGet, "/openapi.json", "")
+	expectStatus(t, w, http.StatusOK)
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Permission Permission `json:"x-permission"`
+			Self       bool       `json:"x-self-allowed"`
+		} `json:"paths"`
+	}
+	decodeBody(t, w, &spec)
+
+	for _, op := range enabledOperations(app.Config) {
+		operation, ok := spec.Paths[op.Path][strings.ToLower(op.Method)]
+		if !ok {
+			t.Errorf("%s %s ausente de /openapi.json", op.Method, op.Path)
+			continue
+		}
+		if operation.Permission != op.Permission || operation.Self != op.Self {
+			t.Errorf("%s %s: x-permission = %q, esperado %q", op.Method, op.Path, operation.Permission, op.Permission)
+		}
+	}
+}
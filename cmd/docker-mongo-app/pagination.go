@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ===========================================
+// PAGINAÇÃO, FILTROS E ORDENAÇÃO DE USUÁRIOS
+// ===========================================
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// userSortFields mapeia os campos JSON de User para os campos no MongoDB
+var userSortFields = map[string]string{
+	"id":         "_id",
+	"name":       "name",
+	"email":      "email",
+	"age":        "age",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// UserListQuery representa os parâmetros aceitos por GET /users
+type UserListQuery struct {
+	Limit         int
+	Offset        int
+	Sort          bson.D
+	Name          string
+	Email         string
+	MinAge        *int
+	MaxAge        *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Modo cursor (keyset): ativado pela presença de ?cursor=
+	CursorMode bool
+	After      *userCursor
+}
+
+// parseUserListQuery lê e valida a query string de GET /users.
+// Aceita limit/offset, page/page_size ou cursor.
+func parseUserListQuery(values url.Values) (UserListQuery, error) {
+	q := UserListQuery{Limit: defaultPageLimit}
+
+	var err error
+	if q.Limit, err = intParam(values, "limit", defaultPageLimit); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(values, "offset", 0); err != nil {
+		return q, err
+	}
+
+	// page/page_size é uma alternativa a limit/offset
+	if values.Get("page") != "" || values.Get("page_size") != "" {
+		page, err := intParam(values, "page", 1)
+		if err != nil {
+			return q, err
+		}
+		if page < 1 {
+			return q, fmt.Errorf("parâmetro page deve ser maior que zero")
+		}
+		if q.Limit, err = intParam(values, "page_size", defaultPageLimit); err != nil {
+			return q, err
+		}
+		q.Offset = (page - 1) * q.Limit
+	}
+
+	if q.Limit < 1 || q.Limit > maxPageLimit {
+		return q, fmt.Errorf("limite deve estar entre 1 e %d", maxPageLimit)
+	}
+	if q.Offset < 0 {
+		return q, fmt.Errorf("parâmetro offset não pode ser negativo")
+	}
+
+	if values.Has("cursor") {
+		// No modo cursor a ordem é fixa e não há offset
+		if values.Get("offset") != "" || values.Get("page") != "" || values.Get("sort") != "" {
+			return q, fmt.Errorf("parâmetros offset, page e sort não são aceitos com cursor")
+		}
+		q.CursorMode = true
+		q.Sort = cursorSort
+		if token := values.Get("cursor"); token != "" {
+			if q.After, err = decodeCursor(token); err != nil {
+				return q, err
+			}
+		}
+	} else if q.Sort, err = parseSort(values.Get("sort")); err != nil {
+		return q, err
+	}
+
+	q.Name = strings.TrimSpace(values.Get("name"))
+	q.Email = strings.TrimSpace(values.Get("email"))
+
+	if q.MinAge, err = optionalIntParam(values, "min_age"); err != nil {
+		return q, err
+	}
+	if q.MaxAge, err = optionalIntParam(values, "max_age"); err != nil {
+		return q, err
+	}
+	if q.CreatedAfter, err = optionalTimeParam(values, "created_after"); err != nil {
+		return q, err
+	}
+	if q.CreatedBefore, err = optionalTimeParam(values, "created_before"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+// parseSort converte "name,-created_at" em um bson.D de ordenação.
+// O prefixo "-" indica ordem decrescente.
+func parseSort(raw string) (bson.D, error) {
+	sort := bson.D{}
+	if raw == "" {
+		raw = "-created_at"
+	}
+
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		direction := 1
+		if strings.HasPrefix(field, "-") {
+			direction = -1
+			field = field[1:]
+		}
+
+		column, ok := userSortFields[field]
+		if !ok {
+			return nil, fmt.Errorf("campo de ordenação inválido: %q", field)
+		}
+		sort = append(sort, bson.E{Key: column, Value: direction})
+	}
+
+	// _id como desempate garante ordem estável entre páginas
+	for _, e := range sort {
+		if e.Key == "_id" {
+			return sort, nil
+		}
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1}), nil
+}
+
+// Filter monta o filtro do MongoDB a partir dos parâmetros
+func (q UserListQuery) Filter() bson.M {
+	filter := bson.M{}
+
+	if q.Name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(q.Name), "$options": "i"}
+	}
+	if q.Email != "" {
+		filter["email"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.Email) + "$", "$options": "i"}
+	}
+
+	age := bson.M{}
+	if q.MinAge != nil {
+		age["$gte"] = *q.MinAge
+	}
+	if q.MaxAge != nil {
+		age["$lte"] = *q.MaxAge
+	}
+	if len(age) > 0 {
+		filter["age"] = age
+	}
+
+	created := bson.M{}
+	if q.CreatedAfter != nil {
+		created["$gte"] = *q.CreatedAfter
+	}
+	if q.CreatedBefore != nil {
+		created["$lte"] = *q.CreatedBefore
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	return filter
+}
+
+// Matches aplica o mesmo filtro de Filter a um usuário em memória
+func (q UserListQuery) Matches(user User) bool {
+	if q.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.Email != "" && !strings.EqualFold(user.Email, q.Email) {
+		return false
+	}
+	if q.MinAge != nil && user.Age < *q.MinAge {
+		return false
+	}
+	if q.MaxAge != nil && user.Age > *q.MaxAge {
+		return false
+	}
+	if q.CreatedAfter != nil && user.CreatedAt.Before(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && user.CreatedAt.After(*q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// FindOptions retorna as opções de paginação e ordenação do Find
+func (q UserListQuery) FindOptions() *options.FindOptions {
+	return options.Find().
+		SetSort(q.Sort).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+}
+
+// pageLinks monta os links next/prev preservando os demais parâmetros
+func pageLinks(r *http.Request, q UserListQuery, total int64) map[string]interface{} {
+	links := map[string]interface{}{"next": nil, "prev": nil}
+
+	if int64(q.Offset+q.Limit) < total {
+		links["next"] = pageURL(r, q.Limit, q.Offset+q.Limit)
+	}
+	if q.Offset > 0 {
+		prev := q.Offset - q.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links["prev"] = pageURL(r, q.Limit, prev)
+	}
+	return links
+}
+
+// pageURL gera a URL da página com limit/offset explícitos
+func pageURL(r *http.Request, limit, offset int) string {
+	values := r.URL.Query()
+	values.Del("page")
+	values.Del("page_size")
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("offset", strconv.Itoa(offset))
+	return r.URL.Path + "?" + values.Encode()
+}
+
+// intParam lê um inteiro da query string com valor padrão
+func intParam(values url.Values, key string, defaultValue int) (int, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parâmetro %s deve ser um número inteiro", key)
+	}
+	return n, nil
+}
+
+// optionalIntParam lê um inteiro opcional da query string
+func optionalIntParam(values url.Values, key string) (*int, error) {
+	if values.Get(key) == "" {
+		return nil, nil
+	}
+	n, err := intParam(values, key, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// optionalTimeParam lê uma data RFC3339 (ou YYYY-MM-DD) opcional da query string
+func optionalTimeParam(values url.Values, key string) (*time.Time, error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("parâmetro %s deve estar no formato RFC3339 ou YYYY-MM-DD", key)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+// ===========================================
+// RATE LIMITING POR CLIENTE
+// ===========================================
+
+// Cada cliente (chave de API, usuário autenticado ou IP) tem um token bucket
+// por política, no mesmo modelo do DomainLimiter do crawler. Os limites são
+// escritos como "<requisições>/<janela>", por exemplo "100/1m": o bucket
+// comporta 100 requisições e recarrega à taxa de 100 por minuto.
+//
+// Como o limite por cliente roda depois da autenticação, as respostas 401
+// têm um bucket próprio por IP (RATE_LIMIT_AUTH_FAILURES), verificado antes
+// do AuthMiddleware: um flood de tokens ou chaves falsas recebe 429 sem
+// chegar à validação (nem à consulta da chave de API no banco).
+
+// rateLimitExempt são rotas nunca limitadas (probes do Docker/Kubernetes)
+var rateLimitExempt = map[string]bool{
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+}
+
+// RateLimit é uma política de limite
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// String formata a política no padrão RateLimit-Policy ("100;w=60")
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(l.Window.Seconds()))
+}
+
+// parseRateLimit converte "100/1m"; "off" (ou "0") desativa o limite
+func parseRateLimit(value string) (RateLimit, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return RateLimit{}, nil
+	}
+
+	count, window, found := strings.Cut(value, "/")
+	if !found {
+		return RateLimit{}, fmt.Errorf("limite %q inválido (use <requisições>/<janela>, ex.: 100/1m)", value)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("limite %q inválido: número de requisições", value)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Second {
+		return RateLimit{}, fmt.Errorf("limite %q inválido: janela deve ser de pelo menos 1s", value)
+	}
+	return RateLimit{Requests: requests, Window: d}, nil
+}
+
+// parseRateLimitRoutes converte "POST /users=10/1m,POST /auth/login=5/1m"
+func parseRateLimitRoutes(value string) (map[string]RateLimit, error) {
+	routeLimits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, limit, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("entrada %q inválida (use MÉTODO /rota=limite)", entry)
+		}
+		parsed, err := parseRateLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		routeLimits[strings.Join(strings.Fields(route), " ")] = parsed
+	}
+	return routeLimits, nil
+}
+
+// limiterEntry guarda o bucket e o último uso, para a remoção dos ociosos
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter gerencia os buckets por cliente e rota
+type RateLimiter struct {
+	defaultLimit RateLimit
+	routeLimits  map[string]RateLimit // chave: "MÉTODO /template"
+	authFailures RateLimit            // respostas 401 por IP
+	trustProxy   bool
+	idleTTL      time.Duration
+
+	mu        sync.Mutex
+	limiters  map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+// NewRateLimiter monta o limitador a partir de RATE_LIMIT_* (já validados
+// por LoadConfig). Retorna nil quando RATE_LIMIT_ENABLED=false.
+func NewRateLimiter(config *Config) *RateLimiter {
+	if !config.RateLimitEnabled {
+		return nil
+	}
+
+	return &RateLimiter{
+		defaultLimit: config.RateLimitDefault,
+		routeLimits:  config.RateLimitRoutes,
+		authFailures: config.RateLimitAuthFailures,
+		trustProxy:   config.TrustProxy,
+		idleTTL:      config.RateLimitIdleTTL,
+		limiters:     make(map[string]*limiterEntry),
+		lastSweep:    time.Now(),
+	}
+}
+
+// policy retorna o limite da rota e o identificador usado nos buckets
+func (rl *RateLimiter) policy(method, route string) (RateLimit, string) {
+	key := method + " " + route
+	if limit, ok := rl.routeLimits[key]; ok {
+		return limit, key
+	}
+	return rl.defaultLimit, "default"
+}
+
+// get retorna ou cria o bucket do cliente e remove os ociosos periodicamente
+func (rl *RateLimiter) get(key string, limit RateLimit, now time.Time) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) >= rl.idleTTL {
+		rl.evictIdle(now)
+	}
+
+	entry, ok := rl.limiters[key]
+	if !ok {
+		every := rate.Every(limit.Window / time.Duration(limit.Requests))
+		entry = &limiterEntry{limiter: rate.NewLimiter(every, limit.Requests)}
+		rl.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// evictIdle remove buckets sem uso há mais de idleTTL (chamar com lock)
+func (rl *RateLimiter) evictIdle(now time.Time) {
+	before := len(rl.limiters)
+	for key, entry := range rl.limiters {
+		if now.Sub(entry.lastSeen) >= rl.idleTTL {
+			delete(rl.limiters, key)
+		}
+	}
+	rl.lastSweep = now
+
+	if removed := before - len(rl.limiters); removed > 0 {
+		slog.Debug("rate limiters ociosos removidos", "removed", removed, "active", len(rl.limiters))
+	}
+}
+
+// clientKey identifica o cliente: chave de API, usuário autenticado ou IP
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if caller, ok := CallerFromContext(r.Context()); ok {
+		if caller.APIKey {
+			return caller.Subject
+		}
+		return "user:" + caller.Subject
+	}
+	return "ip:" + clientIP(r, rl.trustProxy)
+}
+
+// clientIP usa X-Forwarded-For apenas com TRUST_PROXY=true, e só a última
+// entrada: é a que o proxy confiável acrescenta com o IP que se conectou a
+// ele. As anteriores vêm do próprio cliente e podem ser forjadas (um IP novo
+// a cada requisição escaparia do rate limit).
+func clientIP(r *http.Request, trustProxy bool) string {
+	if values := r.Header.Values("X-Forwarded-For"); trustProxy && len(values) > 0 {
+		hops := strings.Split(values[len(values)-1], ",")
+		if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// routeTemplate retorna o template da rota atual do mux
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// AuthFailureMiddleware limita por IP as requisições que terminam em 401.
+// Deve rodar antes do AuthMiddleware: com o bucket vazio a requisição recebe
+// 429 sem ser autenticada; caso contrário, cada 401 consome um token.
+func (rl *RateLimiter) AuthFailureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		limit := rl.authFailures
+		if r.Method == http.MethodOptions || rateLimitExempt[route] || limit.Requests == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		limiter := rl.get("auth-failures|ip:"+clientIP(r, rl.trustProxy), limit, now)
+
+		if tokens := limiter.TokensAt(now); tokens < 1 {
+			retryAfter := max(int(math.Ceil((1-tokens)/float64(limiter.Limit()))), 1)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			metrics.ObserveRateLimited(r.Method, route)
+			slog.WarnContext(r.Context(), "requisição bloqueada por excesso de falhas de autenticação",
+				"route", route, "policy", limit.String(), "retry_after", retryAfter)
+			writeError(w, r, http.StatusTooManyRequests, "Muitas falhas de autenticação; tente novamente mais tarde")
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status == http.StatusUnauthorized {
+			limiter.AllowN(time.Now(), 1)
+		}
+	})
+}
+
+// Middleware aplica o limite e envia os headers RateLimit-* em todas as
+// respostas limitadas. Deve rodar depois do AuthMiddleware para identificar
+// chaves de API e usuários; requisições anônimas são limitadas por IP.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		limit, policyName := rl.policy(r.Method, route)
+		if r.Method == http.MethodOptions || rateLimitExempt[route] || limit.Requests == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		limiter := rl.get(policyName+"|"+rl.clientKey(r), limit, now)
+
+		reservation := limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			reservation.CancelAt(now)
+		}
+
+		tokens := limiter.TokensAt(now)
+		refill := float64(limit.Requests) - math.Max(tokens, 0)
+		reset := math.Ceil(refill / float64(limiter.Limit()))
+
+		w.Header().Set("RateLimit-Policy", limit.String())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(math.Floor(tokens), 0))))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(int(reset)))
+
+		if delay > 0 {
+			retryAfter := int(math.Ceil(delay.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			metrics.ObserveRateLimited(r.Method, route)
+			slog.WarnContext(r.Context(), "requisição bloqueada pelo rate limit",
+				"route", route, "policy", limit.String(), "retry_after", retryAfter)
+			writeError(w, r, http.StatusTooManyRequests, "Muitas requisições; tente novamente mais tarde")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingAPIKeys conta as consultas de chave de API feitas pelo AuthMiddleware
+type countingAPIKeys struct {
+	APIKeyRepository
+	lookups atomic.Int32
+}
+
+func (c *countingAPIKeys) FindByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	c.lookups.Add(1)
+	return c.APIKeyRepository.FindByHash(ctx, keyHash)
+}
+
+// requestFrom envia a requisição a partir do IP informado
+func requestFrom(app *App, ip, method, path string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = ip + ":40000"
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	app.Router.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitAuthFailures(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"AUTH_ENABLED":             "true",
+		"JWT_SECRET":               testJWTSecret,
+		"RATE_LIMIT_ENABLED":       "true",
+		"RATE_LIMIT_DEFAULT":       "3/1m",
+		"RATE_LIMIT_AUTH_FAILURES": "5/1m",
+	})
+	keys := &countingAPIKeys{APIKeyRepository: app.APIKeys}
+	app.APIKeys = keys
+
+	// Chaves falsas: as 5 primeiras chegam à autenticação, as demais não
+	var statuses []int
+	for i := 0; i < 11; i++ {
+		w := requestFrom(app, "192.0.2.10", http.MethodGet, "/users", "X-API-Key", "chave-falsa-"+strconv.Itoa(i))
+		statuses = append(statuses, w.Code)
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Fatal("429 sem Retry-After")
+		}
+	}
+	for i, status := range statuses {
+		want := http.StatusUnauthorized
+		if i >= 5 {
+			want = http.StatusTooManyRequests
+		}
+		if status != want {
+			t.Fatalf("requisição %d: status %d, esperado %d (todos: %v)", i+1, status, want, statuses)
+		}
+	}
+	if n := keys.lookups.Load(); n != 5 {
+		t.Fatalf("FindByHash chamado %d vezes, esperado 5", n)
+	}
+
+	// Sem token também conta como falha, e o bloqueio vale para o IP
+	expectStatus(t, requestFrom(app, "192.0.2.10", http.MethodGet, "/users"), http.StatusTooManyRequests)
+
+	// Outro IP não é afetado
+	expectStatus(t, requestFrom(app, "192.0.2.20", http.MethodGet, "/users"), http.StatusUnauthorized)
+
+	// Rotas /health* nunca são limitadas
+	expectStatus(t, requestFrom(app, "192.0.2.10", http.MethodGet, "/health"), http.StatusOK)
+}
+
+// Com TRUST_PROXY=true vale a entrada acrescentada pelo proxy; as
+// anteriores são do cliente e não criam buckets novos
+func TestRateLimitForgedForwardedFor(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"AUTH_ENABLED":             "true",
+		"JWT_SECRET":               testJWTSecret,
+		"TRUST_PROXY":              "true",
+		"RATE_LIMIT_ENABLED":       "true",
+		"RATE_LIMIT_DEFAULT":       "3/1m",
+		"RATE_LIMIT_AUTH_FAILURES": "3/1m",
+	})
+	const proxy = "10.0.0.1"
+	forged := func(i int) string { return "198.51.100." + strconv.Itoa(i) + ", 203.0.113.7" }
+
+	// Falhas de autenticação
+	for i := 0; i < 3; i++ {
+		w := requestFrom(app, proxy, http.MethodGet, "/users", "X-Forwarded-For", forged(i))
+		expectStatus(t, w, http.StatusUnauthorized)
+	}
+	expectStatus(t, requestFrom(app, proxy, http.MethodGet, "/users", "X-Forwarded-For", forged(99)), http.StatusTooManyRequests)
+	expectStatus(t, requestFrom(app, proxy, http.MethodGet, "/users", "X-Forwarded-For", "203.0.113.8"), http.StatusUnauthorized)
+
+	// Limite por IP das requisições anônimas
+	for i := 0; i < 3; i++ {
+		expectStatus(t, requestFrom(app, proxy, http.MethodGet, "/", "X-Forwarded-For", forged(i)+", 203.0.113.9"), http.StatusOK)
+	}
+	expectStatus(t, requestFrom(app, proxy, http.MethodGet, "/", "X-Forwarded-For", forged(50)+", 203.0.113.9"), http.StatusTooManyRequests)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  []string
+		want       string
+	}{
+		{"sem proxy ignora o header", false, []string{"198.51.100.1"}, "192.0.2.1"},
+		{"sem header", true, nil, "192.0.2.1"},
+		{"uma entrada", true, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"entrada forjada à esquerda", true, []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"vários headers", true, []string{"198.51.100.1", "198.51.100.2, 203.0.113.7"}, "203.0.113.7"},
+		{"última entrada vazia", true, []string{"198.51.100.1, "}, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:40000"
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(r, tt.trustProxy); got != tt.want {
+				t.Fatalf("clientIP = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitAuthenticatedClient(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"AUTH_ENABLED":             "true",
+		"JWT_SECRET":               testJWTSecret,
+		"RATE_LIMIT_ENABLED":       "true",
+		"RATE_LIMIT_DEFAULT":       "3/1m",
+		"RATE_LIMIT_AUTH_FAILURES": "5/1m",
+	})
+	token, err := app.Tokens.Issue(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requisições autenticadas não consomem o bucket de falhas e seguem o
+	// limite por cliente (RATE_LIMIT_DEFAULT)
+	for i := 0; i < 3; i++ {
+		w := requestFrom(app, "192.0.2.30", http.MethodGet, "/users", "Authorization", "Bearer "+token)
+		expectStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(2-i) {
+			t.Fatalf("RateLimit-Remaining = %q na requisição %d", got, i+1)
+		}
+	}
+	w := requestFrom(app, "192.0.2.30", http.MethodGet, "/users", "Authorization", "Bearer "+token)
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if w.Header().Get("RateLimit-Policy") != "3;w=60" {
+		t.Fatalf("RateLimit-Policy = %q", w.Header().Get("RateLimit-Policy"))
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{"100/1m", RateLimit{Requests: 100, Window: time.Minute}, false},
+		{"off", RateLimit{}, false},
+		{"0", RateLimit{}, false},
+		{"100", RateLimit{}, true},
+		{"x/1m", RateLimit{}, true},
+		{"10/500ms", RateLimit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRateLimit(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRateLimit(%q) = %+v, %v", tt.value, got, err)
+		}
+	}
+}
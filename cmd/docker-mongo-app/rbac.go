@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ===========================================
+// PAPÉIS E PERMISSÕES (RBAC)
+// ===========================================
+
+// Permission é uma ação protegida da API, exigida por rota em SetupRoutes
+type Permission string
+
+const (
+	PermUsersRead   Permission = "users:read"
+	PermUsersWrite  Permission = "users:write"
+	PermUsersDelete Permission = "users:delete"
+	PermUsersManage Permission = "users:manage" // papéis e senhas de outros usuários
+	PermConfigRead  Permission = "config:read"
+	PermMetricsRead Permission = "metrics:read"
+	PermAPIKeys     Permission = "apikeys:manage"
+	PermAuditRead   Permission = "audit:read"
+	PermLogsRead    Permission = "logs:read"
+)
+
+// Papéis conhecidos, do mais restrito ao mais amplo
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// rolePermissions define o que cada papel pode fazer
+var rolePermissions = map[string][]Permission{
+	RoleViewer:   {PermUsersRead},
+	RoleOperator: {PermUsersRead, PermUsersWrite, PermMetricsRead, PermLogsRead},
+	RoleAdmin: {
+		PermUsersRead, PermUsersWrite, PermUsersDelete, PermUsersManage,
+		PermConfigRead, PermMetricsRead, PermAPIKeys, PermAuditRead, PermLogsRead,
+	},
+}
+
+// knownPermissions são os escopos aceitos em chaves de API
+var knownPermissions = func() map[Permission]bool {
+	known := make(map[Permission]bool)
+	for _, perms := range rolePermissions {
+		for _, perm := range perms {
+			known[perm] = true
+		}
+	}
+	return known
+}()
+
+// defaultRoles são atribuídos a novos usuários e a usuários sem papel
+var defaultRoles = []string{RoleViewer}
+
+// Caller identifica quem faz a requisição autenticada: um usuário (token
+// JWT, permissões dos papéis) ou um serviço (X-API-Key, permissões dos escopos)
+type Caller struct {
+	Subject     string
+	Email       string
+	Roles       []string
+	APIKey      bool
+	Permissions map[Permission]bool
+}
+
+// NewCaller calcula as permissões a partir dos papéis; papéis desconhecidos
+// são ignorados
+func NewCaller(subject, email string, roles []string) *Caller {
+	caller := &Caller{
+		Subject:     subject,
+		Email:       email,
+		Roles:       roles,
+		Permissions: make(map[Permission]bool),
+	}
+	for _, role := range roles {
+		for _, perm := range rolePermissions[role] {
+			caller.Permissions[perm] = true
+		}
+	}
+	return caller
+}
+
+// Can indica se o chamador possui a permissão
+func (c *Caller) Can(perm Permission) bool {
+	return c.Permissions[perm]
+}
+
+// CallerFromContext retorna o chamador autenticado pelo AuthMiddleware
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey).(*Caller)
+	return caller, ok
+}
+
+// effectiveRoles retorna os papéis do usuário ou defaultRoles se não houver
+func effectiveRoles(user User) []string {
+	if len(user.Roles) == 0 {
+		return defaultRoles
+	}
+	return user.Roles
+}
+
+// Require protege o handler com a permissão informada. Sem autenticação
+// (AUTH_ENABLED=false) não há chamador e a verificação é ignorada.
+func (a *App) Require(perm Permission, handler http.HandlerFunc) http.Handler {
+	return permissionHandler{app: a, perm: perm, handler: handler}
+}
+
+// RequireSelfOr é como Require, mas o próprio usuário da rota ({id}) é
+// aceito sem a permissão. Chaves de API nunca contam como o próprio usuário.
+func (a *App) RequireSelfOr(perm Permission, handler http.HandlerFunc) http.Handler {
+	return permissionHandler{app: a, perm: perm, self: true, handler: handler}
+}
+
+// permissionHandler é o handler criado por Require e RequireSelfOr; guarda
+// a regra para que checkOpenAPICoverage compare a rota com apiOperations
+type permissionHandler struct {
+	app     *App
+	perm    Permission
+	self    bool
+	handler http.HandlerFunc
+}
+
+func (h permissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.app.JWT == nil {
+		h.handler(w, r)
+		return
+	}
+
+	caller, ok := CallerFromContext(r.Context())
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, "Token de acesso ausente")
+		return
+	}
+	if !caller.Can(h.perm) && !(h.self && isSelf(caller, r)) {
+		writeForbidden(w, r, caller, h.perm)
+		return
+	}
+	h.handler(w, r)
+}
+
+// isSelf indica se o usuário autenticado é o {id} da rota
+func isSelf(caller *Caller, r *http.Request) bool {
+	return !caller.APIKey && caller.Subject == mux.Vars(r)["id"]
+}
+
+// writeForbidden responde 403 explicando a permissão que faltou
+func writeForbidden(w http.ResponseWriter, r *http.Request, caller *Caller, perm Permission) {
+	reason := fmt.Sprintf("a operação exige a permissão %s", perm)
+	switch {
+	case caller.APIKey:
+		reason += ", ausente nos escopos da chave de API"
+	case len(caller.Roles) == 0:
+		reason += ", mas o token não possui papéis"
+	default:
+		reason += fmt.Sprintf(", não concedida aos papéis %s", strings.Join(caller.Roles, ", "))
+	}
+
+	writeErrorBody(w, r, http.StatusForbidden, map[string]interface{}{
+		"error":      "Permissão negada",
+		"reason":     reason,
+		"permission": perm,
+	})
+}
+
+// SetUserRolesHandler substitui os papéis de um usuário (PUT /users/{id}/roles).
+// Os novos papéis valem a partir do próximo login ou refresh do usuário.
+func (a *App) SetUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	raw, err := decodeJSONObject(w, r)
+	if err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	roles, errs := parseRoles(raw)
+	if len(errs) > 0 {
+		writePayloadError(w, r, errs)
+		return
+	}
+
+	a.applyUserUpdate(w, r, id, UserPatch{Roles: &roles})
+}
+
+// parseRoles valida o corpo {"roles": [...]}, removendo duplicados
+func parseRoles(raw map[string]json.RawMessage) ([]string, ValidationErrors) {
+	var errs ValidationErrors
+	for key := range raw {
+		if key != "roles" {
+			errs.add(key, "campo desconhecido")
+		}
+	}
+
+	value, ok := raw["roles"]
+	if !ok {
+		errs.add("roles", "campo obrigatório")
+		return nil, errs
+	}
+
+	var roles []string
+	if json.Unmarshal(value, &roles) != nil || roles == nil {
+		errs.add("roles", "deve ser uma lista de textos")
+		return nil, errs
+	}
+
+	for _, role := range roles {
+		if _, known := rolePermissions[role]; !known {
+			errs.add("roles", "papel desconhecido %q (use %s, %s ou %s)", role, RoleViewer, RoleOperator, RoleAdmin)
+		}
+	}
+
+	sort.Strings(roles)
+	return slices.Compact(roles), errs
+}
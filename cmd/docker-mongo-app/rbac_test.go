@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newRBACTestApp habilita a autenticação e emite tokens por papel
+func newRBACTestApp(t *testing.T) (*App, func(user User) string) {
+	t.Helper()
+	app := newTestApp(t, map[string]string{
+		"AUTH_ENABLED": "true",
+		"JWT_SECRET":   testJWTSecret,
+	})
+	bearer := func(user User) string {
+		t.Helper()
+		if user.ID.IsZero() {
+			user.ID = primitive.NewObjectID()
+		}
+		token, err := app.Tokens.Issue(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	return app, bearer
+}
+
+// expectForbidden confere o 403 e a explicação da permissão que faltou
+func expectForbidden(t *testing.T, w *httptest.ResponseRecorder, perm Permission, reason string) {
+	t.Helper()
+	expectStatus(t, w, http.StatusForbidden)
+	var body struct {
+		Error      string     `json:"error"`
+		Reason     string     `json:"reason"`
+		Permission Permission `json:"permission"`
+	}
+	decodeBody(t, w, &body)
+	if body.Error != "Permissão negada" || body.Permission != perm || body.Reason != reason {
+		t.Fatalf("403 inesperado: %+v", body)
+	}
+}
+
+func TestRBACRoutes(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+	operator := bearer(User{Email: "operator@example.com", Roles: []string{RoleOperator}})
+	viewer := bearer(User{Email: "viewer@example.com", Roles: []string{RoleViewer}})
+
+	w := doRequest(app, http.MethodPost, "/users", `{"name":"Alvo","email":"alvo@example.com","age":30}`, "Authorization", admin)
+	expectStatus(t, w, http.StatusCreated)
+	var target User
+	decodeBody(t, w, &target)
+	path := "/users/" + target.ID.Hex()
+	body := `{"name":"Alvo","email":"alvo@example.com","age":31}`
+
+	t.Run("viewer somente leitura", func(t *testing.T) {
+		expectStatus(t, doRequest(app, http.MethodGet, "/users", "", "Authorization", viewer), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, path, "", "Authorization", viewer), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, "/users/search?q=alvo", "", "Authorization", viewer), http.StatusOK)
+
+		reason := "a operação exige a permissão users:write, não concedida aos papéis viewer"
+		expectForbidden(t, doRequest(app, http.MethodPost, "/users", body, "Authorization", viewer), PermUsersWrite, reason)
+		expectForbidden(t, doRequest(app, http.MethodPut, path, body, "Authorization", viewer), PermUsersWrite, reason)
+		expectForbidden(t, doRequest(app, http.MethodPatch, path, `{"age":32}`, "Authorization", viewer), PermUsersWrite, reason)
+		expectForbidden(t, doRequest(app, http.MethodDelete, path, "", "Authorization", viewer), PermUsersDelete,
+			"a operação exige a permissão users:delete, não concedida aos papéis viewer")
+		expectForbidden(t, doRequest(app, http.MethodGet, "/metrics", "", "Authorization", viewer), PermMetricsRead,
+			"a operação exige a permissão metrics:read, não concedida aos papéis viewer")
+	})
+
+	t.Run("operator sem delete", func(t *testing.T) {
+		expectStatus(t, doRequest(app, http.MethodPut, path, body, "Authorization", operator), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodPatch, path, `{"age":33}`, "Authorization", operator), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, "/metrics", "", "Authorization", operator), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, "/logs", "", "Authorization", operator), http.StatusOK)
+
+		expectForbidden(t, doRequest(app, http.MethodDelete, path, "", "Authorization", operator), PermUsersDelete,
+			"a operação exige a permissão users:delete, não concedida aos papéis operator")
+		expectForbidden(t, doRequest(app, http.MethodPut, path+"/roles", `{"roles":["admin"]}`, "Authorization", operator), PermUsersManage,
+			"a operação exige a permissão users:manage, não concedida aos papéis operator")
+		expectForbidden(t, doRequest(app, http.MethodGet, "/audit", "", "Authorization", operator), PermAuditRead,
+			"a operação exige a permissão audit:read, não concedida aos papéis operator")
+		expectForbidden(t, doRequest(app, http.MethodGet, "/api-keys", "", "Authorization", operator), PermAPIKeys,
+			"a operação exige a permissão apikeys:manage, não concedida aos papéis operator")
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		expectStatus(t, doRequest(app, http.MethodGet, "/config", "", "Authorization", admin), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, "/audit", "", "Authorization", admin), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodGet, "/api-keys", "", "Authorization", admin), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodPut, path+"/roles", `{"roles":["operator"]}`, "Authorization", admin), http.StatusOK)
+		expectStatus(t, doRequest(app, http.MethodDelete, path, "", "Authorization", admin), http.StatusNoContent)
+	})
+
+	t.Run("sem token", func(t *testing.T) {
+		expectStatus(t, doRequest(app, http.MethodGet, "/users", ""), http.StatusUnauthorized)
+		expectStatus(t, doRequest(app, http.MethodPut, path+"/password", `{"new_password":"nova-senha-123"}`), http.StatusUnauthorized)
+	})
+}
+
+// PUT /users/{id}/password aceita o próprio usuário ou users:manage
+func TestRBACPasswordRoute(t *testing.T) {
+	app, bearer := newRBACTestApp(t)
+	admin := bearer(User{Email: "admin@example.com", Roles: []string{RoleAdmin}})
+
+	var users []User
+	for _, email := range []string{"eu@example.com", "outro@example.com"} {
+		w := doRequest(app, http.MethodPost, "/users", `{"name":"Usuário","email":"`+email+`","age":30}`, "Authorization", admin)
+		expectStatus(t, w, http.StatusCreated)
+		var user User
+		decodeBody(t, w, &user)
+		users = append(users, user)
+	}
+	me, other := users[0], users[1]
+	myToken := bearer(User{ID: me.ID, Email: me.Email, Roles: []string{RoleViewer}})
+	operator := bearer(User{Email: "operator@example.com", Roles: []string{RoleOperator}})
+	newPassword := `{"new_password":"nova-senha-123"}`
+
+	expectStatus(t, doRequest(app, http.MethodPut, "/users/"+me.ID.Hex()+"/password", newPassword, "Authorization", myToken), http.StatusNoContent)
+	expectForbidden(t, doRequest(app, http.MethodPut, "/users/"+other.ID.Hex()+"/password", newPassword, "Authorization", myToken), PermUsersManage,
+		"a operação exige a permissão users:manage, não concedida aos papéis viewer")
+	expectForbidden(t, doRequest(app, http.MethodPut, "/users/"+other.ID.Hex()+"/password", newPassword, "Authorization", operator), PermUsersManage,
+		"a operação exige a permissão users:manage, não concedida aos papéis operator")
+
+	// users:manage redefine a senha de outro usuário sem a senha atual
+	expectStatus(t, doRequest(app, http.MethodPut, "/users/"+me.ID.Hex()+"/password", `{"new_password":"redefinida-456"}`, "Authorization", admin), http.StatusNoContent)
+
+	// O próprio usuário precisa informar a senha atual
+	expectStatus(t, doRequest(app, http.MethodPut, "/users/"+me.ID.Hex()+"/password", `{"new_password":"outra-senha-789"}`, "Authorization", myToken), http.StatusUnprocessableEntity)
+	expectStatus(t, doRequest(app, http.MethodPut, "/users/"+me.ID.Hex()+"/password",
+		`{"current_password":"redefinida-456","new_password":"outra-senha-789"}`, "Authorization", myToken), http.StatusNoContent)
+}
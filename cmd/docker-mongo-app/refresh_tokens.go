@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ===========================================
+// REFRESH TOKENS
+// ===========================================
+
+// Refresh tokens são valores aleatórios opacos. Apenas o hash SHA-256 é
+// armazenado, então um vazamento do banco não permite renovar sessões.
+// Cada token é de uso único: /auth/refresh o consome e emite outro (rotação).
+
+// ErrRefreshTokenInvalid indica token inexistente, já usado ou expirado
+var ErrRefreshTokenInvalid = errors.New("refresh token inválido")
+
+// RefreshToken é o registro armazenado de um refresh token emitido
+type RefreshToken struct {
+	TokenHash string             `bson:"token_hash"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+// RefreshTokenRepository abstrai o armazenamento dos refresh tokens
+type RefreshTokenRepository interface {
+	// Save grava um novo token
+	Save(ctx context.Context, token RefreshToken) error
+
+	// Consume remove o token e o retorna; ErrRefreshTokenInvalid se não
+	// existir ou estiver expirado. A remoção atômica impede reuso.
+	Consume(ctx context.Context, tokenHash string) (RefreshToken, error)
+
+	// DeleteByUser revoga todos os tokens do usuário (ex.: troca de senha)
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// hashRefreshToken calcula o hash armazenado para o token enviado ao cliente
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MongoRefreshTokenRepository guarda os tokens na coleção "refresh_tokens".
+// O índice TTL em expires_at remove os expirados automaticamente.
+type MongoRefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRefreshTokenRepository cria o repositório sobre a coleção refresh_tokens
+func NewMongoRefreshTokenRepository(db *mongo.Database) *MongoRefreshTokenRepository {
+	return &MongoRefreshTokenRepository{collection: db.Collection("refresh_tokens")}
+}
+
+// Save grava um novo token
+func (m *MongoRefreshTokenRepository) Save(ctx context.Context, token RefreshToken) error {
+	_, err := m.collection.InsertOne(ctx, token)
+	return err
+}
+
+// Consume remove e retorna o token com FindOneAndDelete
+func (m *MongoRefreshTokenRepository) Consume(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	var token RefreshToken
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return token, err
+	}
+	// O monitor TTL do MongoDB roda a cada 60s; a expiração é conferida aqui
+	if time.Now().After(token.ExpiresAt) {
+		return token, ErrRefreshTokenInvalid
+	}
+	return token, nil
+}
+
+// DeleteByUser revoga todos os tokens do usuário
+func (m *MongoRefreshTokenRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// MemoryRefreshTokenRepository guarda os tokens em memória (USER_STORE=memory)
+type MemoryRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]RefreshToken
+}
+
+// NewMemoryRefreshTokenRepository cria um repositório vazio
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{tokens: make(map[string]RefreshToken)}
+}
+
+// Save grava um novo token
+func (m *MemoryRefreshTokenRepository) Save(ctx context.Context, token RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[token.TokenHash] = token
+	return nil
+}
+
+// Consume remove e retorna o token
+func (m *MemoryRefreshTokenRepository) Consume(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return RefreshToken{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	delete(m.tokens, tokenHash)
+	if time.Now().After(token.ExpiresAt) {
+		return token, ErrRefreshTokenInvalid
+	}
+	return token, nil
+}
+
+// DeleteByUser revoga todos os tokens do usuário
+func (m *MemoryRefreshTokenRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, token := range m.tokens {
+		if token.UserID == userID {
+			delete(m.tokens, hash)
+		}
+	}
+	return nil
+}
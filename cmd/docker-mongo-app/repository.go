@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ===========================================
+// REPOSITÓRIO DE USUÁRIOS
+// ===========================================
+
+// ErrUserNotFound indica que nenhum usuário possui o ID informado
+var ErrUserNotFound = errors.New("usuário não encontrado")
+
+// DuplicateKeyError indica violação de um campo único (ex.: email)
+type DuplicateKeyError struct {
+	Field string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Field == "" {
+		return "valor duplicado"
+	}
+	return fmt.Sprintf("valor duplicado para o campo %s", e.Field)
+}
+
+// UserRepository abstrai o armazenamento de usuários.
+// Existem duas implementações: MongoUserRepository (produção) e
+// MemoryUserRepository (testes e execução local sem Docker).
+type UserRepository interface {
+	// Create insere o usuário e preenche user.ID
+	Create(ctx context.Context, user *User) error
+
+	// FindByID retorna ErrUserNotFound se o usuário não existir
+	FindByID(ctx context.Context, id primitive.ObjectID) (User, error)
+
+	// Update aplica os campos não-nil do patch e atualiza UpdatedAt numa
+	// única operação atômica, retornando o estado anterior e o novo
+	Update(ctx context.Context, id primitive.ObjectID, patch UserPatch) (before, after User, err error)
+
+	// Delete remove o usuário e o retorna; ErrUserNotFound se não existir
+	Delete(ctx context.Context, id primitive.ObjectID) (User, error)
+
+	// List retorna a página (limit/offset) e o total de usuários do filtro
+	List(ctx context.Context, query UserListQuery) ([]User, int64, error)
+
+	// ListAfter retorna até query.Limit usuários depois de query.After,
+	// na ordem created_at DESC, _id DESC
+	ListAfter(ctx context.Context, query UserListQuery) ([]User, error)
+
+	// FindByEmail retorna ErrUserNotFound se nenhum usuário usar o email
+	FindByEmail(ctx context.Context, email string) (User, error)
+
+	// SetPassword grava o hash bcrypt e zera as falhas de login
+	SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error
+
+	// RecordLoginFailure incrementa as falhas; ao atingir maxAttempts o
+	// usuário é bloqueado até lockUntil e o contador volta a zero
+	RecordLoginFailure(ctx context.Context, id primitive.ObjectID, maxAttempts int, lockUntil time.Time) (User, error)
+
+	// ResetLoginFailures zera as falhas e remove o bloqueio
+	ResetLoginFailures(ctx context.Context, id primitive.ObjectID) error
+
+	// Search busca por nome ou email, do mais ao menos relevante, e informa
+	// o modo usado (SearchModeText ou SearchModeRegex)
+	Search(ctx context.Context, query UserSearchQuery) ([]UserSearchResult, string, error)
+}
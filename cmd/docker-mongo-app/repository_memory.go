@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryUserRepository guarda usuários em memória (thread-safe).
+// Reproduz as regras do MongoDB usadas pela API: email único,
+// filtros, ordenação, paginação por offset ou cursor e cancelamento
+// pelo contexto.
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]User
+}
+
+// NewMemoryUserRepository cria um repositório vazio
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[primitive.ObjectID]User),
+	}
+}
+
+// Create insere um novo usuário
+func (m *MemoryUserRepository) Create(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.emailTaken(user.Email, primitive.NilObjectID) {
+		return &DuplicateKeyError{Field: "email"}
+	}
+
+	user.ID = primitive.NewObjectID()
+	m.users[user.ID] = *user
+	return nil
+}
+
+// FindByID busca um usuário pelo ID
+func (m *MemoryUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Update aplica os campos não-nil do patch sob o mesmo lock da leitura
+// do estado anterior
+func (m *MemoryUserRepository) Update(ctx context.Context, id primitive.ObjectID, patch UserPatch) (User, User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, User{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, ok := m.users[id]
+	if !ok {
+		return User{}, User{}, ErrUserNotFound
+	}
+
+	if patch.Email != nil && m.emailTaken(*patch.Email, id) {
+		return User{}, User{}, &DuplicateKeyError{Field: "email"}
+	}
+
+	after := before
+	patch.apply(&after, time.Now())
+	m.users[id] = after
+	return before, after, nil
+}
+
+// Delete remove um usuário pelo ID e retorna o usuário removido
+func (m *MemoryUserRepository) Delete(ctx context.Context, id primitive.ObjectID) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	delete(m.users, id)
+	return user, nil
+}
+
+// List retorna uma página de usuários e o total do filtro
+func (m *MemoryUserRepository) List(ctx context.Context, query UserListQuery) ([]User, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	users := m.matching(query, nil)
+	sortUsers(users, query.Sort)
+
+	total := int64(len(users))
+	return paginate(users, query.Offset, query.Limit), total, nil
+}
+
+// ListAfter retorna usuários depois do cursor (paginação keyset)
+func (m *MemoryUserRepository) ListAfter(ctx context.Context, query UserListQuery) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	users := m.matching(query, query.After)
+	sortUsers(users, cursorSort)
+
+	return paginate(users, 0, query.Limit), nil
+}
+
+// FindByEmail busca um usuário pelo email
+func (m *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+// SetPassword grava o hash da senha e limpa o bloqueio
+func (m *MemoryUserRepository) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return m.modify(ctx, id, func(user *User) {
+		user.PasswordHash = hash
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+		user.UpdatedAt = time.Now()
+	})
+}
+
+// RecordLoginFailure incrementa as falhas e bloqueia ao atingir o limite
+func (m *MemoryUserRepository) RecordLoginFailure(ctx context.Context, id primitive.ObjectID, maxAttempts int, lockUntil time.Time) (User, error) {
+	var result User
+	err := m.modify(ctx, id, func(user *User) {
+		user.FailedLogins++
+		if user.FailedLogins >= maxAttempts {
+			user.FailedLogins = 0
+			user.LockedUntil = &lockUntil
+		}
+		result = *user
+	})
+	return result, err
+}
+
+// ResetLoginFailures remove o contador de falhas e o bloqueio
+func (m *MemoryUserRepository) ResetLoginFailures(ctx context.Context, id primitive.ObjectID) error {
+	return m.modify(ctx, id, func(user *User) {
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	})
+}
+
+// Search usa sempre o modo regex (não há índice de texto em memória)
+func (m *MemoryUserRepository) Search(ctx context.Context, query UserSearchQuery) ([]UserSearchResult, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matcher := newRegexMatcher(query)
+	results := []UserSearchResult{}
+	for _, user := range m.users {
+		if score := matcher.score(user); score > 0 {
+			results = append(results, UserSearchResult{User: user, Score: score})
+		}
+	}
+	sortSearchResults(results)
+	return results[:min(len(results), query.Limit)], SearchModeRegex, nil
+}
+
+// modify aplica fn ao usuário sob lock de escrita
+func (m *MemoryUserRepository) modify(ctx context.Context, id primitive.ObjectID, fn func(*User)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	fn(&user)
+	m.users[id] = user
+	return nil
+}
+
+// matching copia os usuários que atendem ao filtro e estão após o cursor
+func (m *MemoryUserRepository) matching(query UserListQuery, after *userCursor) []User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := []User{}
+	for _, user := range m.users {
+		if !query.Matches(user) {
+			continue
+		}
+		if after != nil && !isAfterCursor(user, after) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// emailTaken verifica se outro usuário já usa o email (chamar com lock)
+func (m *MemoryUserRepository) emailTaken(email string, except primitive.ObjectID) bool {
+	for id, user := range m.users {
+		if id != except && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// isAfterCursor replica o filtro keyset de cursorFilter
+func isAfterCursor(user User, after *userCursor) bool {
+	if user.CreatedAt.Equal(after.CreatedAt) {
+		return user.ID.Hex() < after.ID.Hex()
+	}
+	return user.CreatedAt.Before(after.CreatedAt)
+}
+
+// sortUsers ordena conforme a especificação bson.D usada no MongoDB
+func sortUsers(users []User, spec bson.D) {
+	sort.SliceStable(users, func(i, j int) bool {
+		for _, e := range spec {
+			c := compareUserField(users[i], users[j], e.Key)
+			if c == 0 {
+				continue
+			}
+			if e.Value == -1 {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// compareUserField compara dois usuários pelo campo do MongoDB informado
+func compareUserField(a, b User, field string) int {
+	switch field {
+	case "_id":
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "email":
+		return strings.Compare(a.Email, b.Email)
+	case "age":
+		return a.Age - b.Age
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	return 0
+}
+
+// paginate aplica offset/limit sobre a lista já ordenada
+func paginate(users []User, offset, limit int) []User {
+	if offset >= len(users) {
+		return []User{}
+	}
+	end := offset + limit
+	if end > len(users) {
+		end = len(users)
+	}
+	return users[offset:end]
+}
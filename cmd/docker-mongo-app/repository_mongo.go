@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUserRepository armazena usuários na coleção "users" do MongoDB
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository cria o repositório sobre a coleção users
+func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{collection: db.Collection("users")}
+}
+
+// Create insere um novo usuário
+func (m *MongoUserRepository) Create(ctx context.Context, user *User) error {
+	result, err := m.collection.InsertOne(ctx, user)
+	if err != nil {
+		return translateMongoError(err)
+	}
+
+	user.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// FindByID busca um usuário pelo ID
+func (m *MongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (User, error) {
+	var user User
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	return user, translateMongoError(err)
+}
+
+// Update aplica o patch com $set. O findAndModify retorna o documento
+// anterior à escrita; o novo estado é esse documento com o mesmo $set
+// aplicado, então os dois descrevem exatamente a alteração gravada.
+func (m *MongoUserRepository) Update(ctx context.Context, id primitive.ObjectID, patch UserPatch) (User, User, error) {
+	// Precisão de milissegundos, a mesma do BSON
+	now := time.Now().Truncate(time.Millisecond)
+	fields := bson.M{"updated_at": now}
+	if patch.Name != nil {
+		fields["name"] = *patch.Name
+	}
+	if patch.Email != nil {
+		fields["email"] = *patch.Email
+	}
+	if patch.Age != nil {
+		fields["age"] = *patch.Age
+	}
+	if patch.Roles != nil {
+		fields["roles"] = *patch.Roles
+	}
+
+	var before User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&before)
+	if err != nil {
+		return User{}, User{}, translateMongoError(err)
+	}
+
+	after := before
+	patch.apply(&after, now)
+	return before, after, nil
+}
+
+// Delete remove um usuário pelo ID e retorna o documento removido
+func (m *MongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID) (User, error) {
+	var user User
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&user)
+	return user, translateMongoError(err)
+}
+
+// List retorna uma página de usuários e o total do filtro
+func (m *MongoUserRepository) List(ctx context.Context, query UserListQuery) ([]User, int64, error) {
+	filter := query.Filter()
+
+	total, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users, err := m.find(ctx, filter, query.FindOptions())
+	return users, total, err
+}
+
+// ListAfter retorna usuários depois do cursor (paginação keyset)
+func (m *MongoUserRepository) ListAfter(ctx context.Context, query UserListQuery) ([]User, error) {
+	opts := options.Find().
+		SetSort(cursorSort).
+		SetLimit(int64(query.Limit))
+
+	return m.find(ctx, cursorFilter(query.Filter(), query.After), opts)
+}
+
+// find executa o Find e decodifica todos os documentos do cursor
+func (m *MongoUserRepository) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]User, error) {
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindByEmail busca um usuário pelo email (já normalizado em minúsculas)
+func (m *MongoUserRepository) FindByEmail(ctx context.Context, email string) (User, error) {
+	var user User
+	err := m.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	return user, translateMongoError(err)
+}
+
+// SetPassword grava o hash da senha e limpa o bloqueio
+func (m *MongoUserRepository) SetPassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	update := bson.M{
+		"$set":   bson.M{"password_hash": hash, "updated_at": time.Now()},
+		"$unset": bson.M{"failed_logins": "", "locked_until": ""},
+	}
+	result, err := m.collection.UpdateByID(ctx, id, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RecordLoginFailure incrementa failed_logins e bloqueia ao atingir o limite
+func (m *MongoUserRepository) RecordLoginFailure(ctx context.Context, id primitive.ObjectID, maxAttempts int, lockUntil time.Time) (User, error) {
+	var user User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"failed_logins": 1}}, opts).Decode(&user)
+	if err != nil {
+		return user, translateMongoError(err)
+	}
+
+	if user.FailedLogins >= maxAttempts {
+		update := bson.M{
+			"$set":   bson.M{"locked_until": lockUntil},
+			"$unset": bson.M{"failed_logins": ""},
+		}
+		err = m.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&user)
+	}
+	return user, translateMongoError(err)
+}
+
+// ResetLoginFailures remove o contador de falhas e o bloqueio
+func (m *MongoUserRepository) ResetLoginFailures(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$unset": bson.M{"failed_logins": "", "locked_until": ""}}
+	_, err := m.collection.UpdateByID(ctx, id, update)
+	return err
+}
+
+// Search usa $text ordenado por textScore; sem índice de texto (erro
+// IndexNotFound) repete a busca por regex
+func (m *MongoUserRepository) Search(ctx context.Context, query UserSearchQuery) ([]UserSearchResult, string, error) {
+	results, err := m.textSearch(ctx, query)
+	if err == nil {
+		return results, SearchModeText, nil
+	}
+
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) || !serverErr.HasErrorCode(indexNotFoundCode) {
+		return nil, "", err
+	}
+
+	slog.WarnContext(ctx, "índice de texto ausente, usando busca por regex")
+	results, err = m.regexSearch(ctx, query)
+	return results, SearchModeRegex, err
+}
+
+// indexNotFoundCode é o código do MongoDB para $text sem índice de texto
+const indexNotFoundCode = 27
+
+// textSearch executa a busca com o índice de texto
+func (m *MongoUserRepository) textSearch(ctx context.Context, query UserSearchQuery) ([]UserSearchResult, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := m.collection.Find(ctx, bson.M{"$text": bson.M{"$search": query.Text}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		User  `bson:",inline"`
+		Score float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	results := make([]UserSearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = UserSearchResult{User: doc.User, Score: doc.Score}
+	}
+	return results, nil
+}
+
+// regexSearch busca por prefixo de palavra. A relevância é calculada no
+// servidor (RegexScore) e todos os documentos encontrados são ordenados
+// antes do $limit, então os melhores resultados nunca ficam de fora; o
+// custo é percorrer os documentos que casam com o filtro, aceitável para o
+// modo de contingência sem índice de texto.
+func (m *MongoUserRepository) regexSearch(ctx context.Context, query UserSearchQuery) ([]UserSearchResult, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query.RegexFilter()}},
+		{{Key: "$addFields", Value: bson.M{"score": query.RegexScore()}}},
+		{{Key: "$sort", Value: regexSearchSort}},
+		{{Key: "$limit", Value: query.Limit}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		User  `bson:",inline"`
+		Score float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	results := make([]UserSearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = UserSearchResult{User: doc.User, Score: doc.Score}
+	}
+	return results, nil
+}
+
+// translateMongoError converte erros do driver nos erros do repositório
+func translateMongoError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == mongo.ErrNoDocuments:
+		return ErrUserNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return &DuplicateKeyError{Field: duplicateKeyField(err)}
+	default:
+		return err
+	}
+}
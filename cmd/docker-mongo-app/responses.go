@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ===========================================
+// RESPOSTAS DE ERRO
+// ===========================================
+
+// writeError responde com o envelope de erro padrão:
+// {"error": "...", "request_id": "..."}
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeErrorBody(w, r, status, map[string]interface{}{"error": message})
+}
+
+// writeErrorBody responde com um corpo de erro JSON, acrescentando o
+// request_id para que o suporte encontre a requisição nos logs
+func writeErrorBody(w http.ResponseWriter, r *http.Request, status int, body map[string]interface{}) {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		body["request_id"] = id
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ===========================================
+// RETRY COM BACKOFF EXPONENCIAL
+// ===========================================
+
+// RetryPolicy controla as tentativas de conexão com o MongoDB
+type RetryPolicy struct {
+	MaxAttempts    int           // 0 = tentar indefinidamente
+	InitialBackoff time.Duration // espera após a primeira falha
+	MaxBackoff     time.Duration // teto da espera entre tentativas
+	AttemptTimeout time.Duration // prazo de cada ping
+}
+
+// retryPolicyFromConfig monta a política a partir de MONGO_CONNECT_*
+func retryPolicyFromConfig(config *Config) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    config.MongoConnectRetries,
+		InitialBackoff: config.MongoRetryBackoff,
+		MaxBackoff:     config.MongoRetryMaxBackoff,
+		AttemptTimeout: 10 * time.Second,
+	}
+}
+
+// Backoff retorna a espera antes da próxima tentativa (attempt começa em 1).
+// Dobra a cada falha até MaxBackoff e aplica jitter entre 50% e 100% do valor
+// para que várias réplicas não reconectem ao mesmo tempo.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// WaitForMongo faz ping no MongoDB até obter resposta, esgotar as tentativas
+// ou o contexto ser cancelado.
+func WaitForMongo(ctx context.Context, client *mongo.Client, policy RetryPolicy) error {
+	var lastErr error
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, policy.AttemptTimeout)
+		lastErr = client.Ping(pingCtx, nil)
+		cancel()
+
+		if lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if policy.MaxAttempts > 0 && attempt == policy.MaxAttempts {
+			break
+		}
+
+		wait := policy.Backoff(attempt)
+		slog.Warn("MongoDB indisponível, nova tentativa agendada",
+			"attempt", attempt,
+			"max_attempts", attemptsLabel(policy.MaxAttempts),
+			"retry_in", wait.Round(time.Millisecond).String(),
+			"error", lastErr)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("MongoDB indisponível após %d tentativas: %v", policy.MaxAttempts, lastErr)
+}
+
+// attemptsLabel formata o total de tentativas para os logs
+func attemptsLabel(max int) string {
+	if max == 0 {
+		return "∞"
+	}
+	return strconv.Itoa(max)
+}
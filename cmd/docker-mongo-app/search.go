@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ===========================================
+// BUSCA TEXTUAL DE USUÁRIOS
+// ===========================================
+
+// A busca usa o índice de texto { name: "text", email: "text" } com $text,
+// ordenando pela relevância (textScore). Sem o índice (ex.: modo degradado
+// antes de ensureIndexes ou USER_STORE=memory) a busca cai para regex por
+// prefixo de palavra, com a relevância calculada por agregação e todo o
+// conjunto encontrado ordenado antes do limite. Nos dois modos acentos são
+// ignorados: "joao" encontra "João" (o índice de texto v3 já é insensível a
+// diacríticos).
+
+const (
+	SearchModeText  = "text"
+	SearchModeRegex = "regex"
+
+	maxSearchLength = 100
+	maxSearchTerms  = 5
+)
+
+// UserSearchQuery é a consulta de GET /users/search
+type UserSearchQuery struct {
+	Text  string
+	Terms []string // termos em minúsculas e sem acentos
+	Limit int
+}
+
+// UserSearchResult é um usuário encontrado com sua relevância e os trechos
+// destacados com <em>
+type UserSearchResult struct {
+	User       User              `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// accentClasses mapeia cada letra base para a classe com suas variações
+var accentClasses = map[rune]string{
+	'a': "aáàâãäAÁÀÂÃÄ",
+	'e': "eéèêëEÉÈÊË",
+	'i': "iíìîïIÍÌÎÏ",
+	'o': "oóòôõöOÓÒÔÕÖ",
+	'u': "uúùûüUÚÙÛÜ",
+	'c': "cçCÇ",
+	'n': "nñNÑ",
+}
+
+// baseLetter remove o acento de uma letra (ã -> a), se conhecida
+func baseLetter(r rune) rune {
+	for base, class := range accentClasses {
+		if strings.ContainsRune(class, r) {
+			return base
+		}
+	}
+	return r
+}
+
+// foldAccents normaliza o texto para minúsculas sem acentos
+func foldAccents(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		b.WriteRune(baseLetter(r))
+	}
+	return b.String()
+}
+
+// accentPattern converte um termo em regex que aceita qualquer acentuação;
+// a sintaxe é comum ao MongoDB (PCRE) e ao pacote regexp (RE2)
+func accentPattern(term string) string {
+	var b strings.Builder
+	for _, r := range term {
+		if class, ok := accentClasses[r]; ok {
+			b.WriteString("[" + class + "]")
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// wordStart casa o início de uma palavra do nome ou de uma parte do email
+const wordStart = `(^|[\s.@_+-])`
+
+// wordPrefixPattern exige que o termo comece uma palavra (ou parte do email)
+func wordPrefixPattern(term string) string {
+	return wordStart + accentPattern(term)
+}
+
+// wholeWordPattern exige que o termo seja uma palavra inteira do nome
+func wholeWordPattern(term string) string {
+	return wordPrefixPattern(term) + `( |$)`
+}
+
+// parseUserSearchQuery valida q e limit
+func parseUserSearchQuery(values url.Values) (UserSearchQuery, error) {
+	q := UserSearchQuery{Text: strings.TrimSpace(values.Get("q"))}
+
+	if q.Text == "" {
+		return q, fmt.Errorf("parâmetro q é obrigatório")
+	}
+	if utf8.RuneCountInString(q.Text) > maxSearchLength {
+		return q, fmt.Errorf("parâmetro q deve ter no máximo %d caracteres", maxSearchLength)
+	}
+
+	var err error
+	if q.Limit, err = intParam(values, "limit", defaultPageLimit); err != nil {
+		return q, err
+	}
+	if q.Limit < 1 || q.Limit > maxPageLimit {
+		return q, fmt.Errorf("limite deve estar entre 1 e %d", maxPageLimit)
+	}
+
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(foldAccents(q.Text)) {
+		if !seen[term] && len(q.Terms) < maxSearchTerms {
+			seen[term] = true
+			q.Terms = append(q.Terms, term)
+		}
+	}
+	return q, nil
+}
+
+// RegexFilter monta o filtro do modo regex: todos os termos precisam
+// aparecer no nome ou no email
+func (q UserSearchQuery) RegexFilter() bson.M {
+	and := bson.A{}
+	for _, term := range q.Terms {
+		regex := bson.M{"$regex": wordPrefixPattern(term), "$options": "i"}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"name": regex},
+			bson.M{"email": regex},
+		}})
+	}
+	return bson.M{"$and": and}
+}
+
+// Relevância no modo regex, por termo: palavra inteira no nome vale 1.5,
+// prefixo no nome 1 e prefixo no email 0.5. RegexScore (MongoDB) e
+// regexMatcher.score (memória) precisam calcular o mesmo valor.
+const (
+	scoreWholeWord   float64 = 1.5
+	scoreNamePrefix  float64 = 1
+	scoreEmailPrefix float64 = 0.5
+)
+
+// RegexScore é a expressão de agregação que calcula a relevância no
+// servidor, para que todo o conjunto encontrado seja ordenado antes do limite
+func (q UserSearchQuery) RegexScore() bson.M {
+	regexMatch := func(field, pattern string) bson.M {
+		return bson.M{"$regexMatch": bson.M{"input": field, "regex": pattern, "options": "i"}}
+	}
+
+	terms := bson.A{}
+	for _, term := range q.Terms {
+		terms = append(terms, bson.M{"$cond": bson.A{
+			regexMatch("$name", wordPrefixPattern(term)),
+			bson.M{"$cond": bson.A{regexMatch("$name", wholeWordPattern(term)), scoreWholeWord, scoreNamePrefix}},
+			bson.M{"$cond": bson.A{regexMatch("$email", wordPrefixPattern(term)), scoreEmailPrefix, 0.0}},
+		}})
+	}
+	return bson.M{"$add": terms}
+}
+
+// regexSearchSort é a ordem dos resultados no modo regex: relevância, nome
+// e, no empate, _id, para que a ordem seja sempre a mesma
+var regexSearchSort = bson.D{{Key: "score", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+
+// regexMatcher compila os termos para uso em memória
+type regexMatcher struct {
+	prefix []*regexp.Regexp
+	whole  []*regexp.Regexp
+}
+
+func newRegexMatcher(q UserSearchQuery) regexMatcher {
+	m := regexMatcher{}
+	for _, term := range q.Terms {
+		m.prefix = append(m.prefix, regexp.MustCompile("(?i)"+wordPrefixPattern(term)))
+		m.whole = append(m.whole, regexp.MustCompile("(?i)"+wholeWordPattern(term)))
+	}
+	return m
+}
+
+// score retorna a relevância do usuário no modo regex (0 = não atende todos
+// os termos), com os mesmos pesos de RegexScore
+func (m regexMatcher) score(user User) float64 {
+	var total float64
+	for i, re := range m.prefix {
+		switch {
+		case m.whole[i].MatchString(user.Name):
+			total += scoreWholeWord
+		case re.MatchString(user.Name):
+			total += scoreNamePrefix
+		case re.MatchString(user.Email):
+			total += scoreEmailPrefix
+		default:
+			return 0
+		}
+	}
+	return total
+}
+
+// sortSearchResults ordena como regexSearchSort: relevância, nome e ID
+func sortSearchResults(results []UserSearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.User.Name != b.User.Name {
+			return a.User.Name < b.User.Name
+		}
+		return a.User.ID.Hex() < b.User.ID.Hex()
+	})
+}
+
+// highlight envolve os trechos que casam com algum termo em <em>, escapando
+// o restante do texto para exibição segura em HTML. Usa o mesmo início de
+// palavra da busca: "an" destaca "Ana", mas não o meio de "Mariana"
+func highlight(text string, terms []string) (string, bool) {
+	if len(terms) == 0 {
+		return "", false
+	}
+
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = accentPattern(term)
+	}
+	// Termos mais longos primeiro para destacar a maior ocorrência
+	sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+	re := regexp.MustCompile("(?i)" + wordStart + "(" + strings.Join(patterns, "|") + ")")
+
+	// O grupo 2 é o termo, sem o separador que o precede
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(html.EscapeString(text[last:m[4]]))
+		b.WriteString("<em>" + html.EscapeString(text[m[4]:m[5]]) + "</em>")
+		last = m[5]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), true
+}
+
+// SearchUsersHandler busca usuários por nome ou email (GET /users/search?q=)
+func (a *App) SearchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseUserSearchQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	results, mode, err := a.Users.Search(ctx, query)
+	if err != nil {
+		writeRepositoryError(w, r, "Erro ao buscar usuários", err)
+		return
+	}
+
+	for i := range results {
+		highlights := make(map[string]string)
+		if fragment, ok := highlight(results[i].User.Name, query.Terms); ok {
+			highlights["name"] = fragment
+		}
+		if fragment, ok := highlight(results[i].User.Email, query.Terms); ok {
+			highlights["email"] = fragment
+		}
+		results[i].Highlights = highlights
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":     query.Text,
+		"mode":      mode,
+		"results":   results,
+		"count":     len(results),
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
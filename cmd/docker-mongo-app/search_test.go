@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testRegexSearch verifica a ordenação do modo regex, comum aos dois
+// repositórios: todos os acertos são ranqueados antes do limite
+func testRegexSearch(t *testing.T, repo UserRepository) {
+	ctx := context.Background()
+	insert := func(name, email string) {
+		t.Helper()
+		user := User{Name: name, Email: email, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := repo.Create(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Muitos acertos só no email antes dos acertos no nome
+	for i := 0; i < 30; i++ {
+		insert("Cliente", "ana"+primitive.NewObjectID().Hex()+"@example.com")
+	}
+	insert("Anabela Souza", "anabela@example.com")
+	insert("Ana Souza", "souza1@example.com")
+	insert("Ana Souza", "souza2@example.com")
+	insert("Bruno", "bruno@example.com")
+
+	query, err := parseUserSearchQuery(url.Values{"q": {"ana"}, "limit": {"3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, mode, err := repo.Search(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != SearchModeRegex {
+		t.Fatalf("modo = %q, esperado %q", mode, SearchModeRegex)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.User.Email)
+	}
+	want := "souza1@example.com,souza2@example.com,anabela@example.com"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("resultados = %v, esperado %s", got, want)
+	}
+	if results[0].Score != scoreWholeWord || results[2].Score != scoreNamePrefix {
+		t.Fatalf("scores = %v, %v", results[0].Score, results[2].Score)
+	}
+}
+
+func TestMemoryRegexSearch(t *testing.T) {
+	testRegexSearch(t, NewMemoryUserRepository())
+}
+
+// TestMongoRegexSearch roda contra um MongoDB real quando MONGO_TEST_URI
+// está definido; sem o índice de texto a busca usa o modo regex
+func TestMongoRegexSearch(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI não definido")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	db := client.Database("app_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() { db.Drop(ctx) })
+	testRegexSearch(t, NewMongoUserRepository(db))
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Ana Souza", []string{"an"}, "<em>An</em>a Souza"},
+		// Só o início de palavras, como na busca por regex
+		{"Mariana", []string{"an"}, ""},
+		{"Mariana Ana", []string{"an"}, "Mariana <em>An</em>a"},
+		{"João Silva", []string{"joao", "sil"}, "<em>João</em> <em>Sil</em>va"},
+		{"maria.ana@example.com", []string{"an"}, "maria.<em>an</em>a@example.com"},
+		{"ana+ana@example.com", []string{"ana"}, "<em>ana</em>+<em>ana</em>@example.com"},
+		{"Jo Joana", []string{"jo", "joana"}, "<em>Jo</em> <em>Joana</em>"},
+		{"<b>Ana</b>", []string{"ana"}, ""},
+		{"Ana <b>", []string{"ana"}, "<em>Ana</em> &lt;b&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := highlight(tt.text, tt.terms)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Fatalf("highlight(%q, %q) = %q, %v; esperado %q", tt.text, tt.terms, got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ===========================================
+// SERVIDOR HTTP E GRACEFUL SHUTDOWN
+// ===========================================
+
+// NewHTTPServer cria o http.Server com timeouts derivados de API_TIMEOUT.
+// O WriteTimeout tem uma folga sobre o API_TIMEOUT para que a resposta de
+// erro de um handler que estourou o prazo ainda consiga ser escrita.
+func NewHTTPServer(addr string, handler http.Handler, config *Config) *http.Server {
+	apiTimeout := config.APITimeout
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       apiTimeout,
+		WriteTimeout:      apiTimeout + 5*time.Second,
+		IdleTimeout:       2 * apiTimeout,
+	}
+}
+
+// runServer inicia o servidor e, ao receber SIGINT/SIGTERM (ex.: docker
+// compose stop), para de aceitar conexões, aguarda as requisições em
+// andamento até shutdownTimeout, grava os logs pendentes e desconecta o
+// cliente MongoDB.
+func runServer(srv *http.Server, app *App, shutdownTimeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+		slog.Info("sinal recebido, encerrando servidor", "shutdown_timeout", shutdownTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var shutdownErr error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("requisições não finalizadas dentro do prazo", "error", err)
+		shutdownErr = err
+	}
+
+	if app.LogSink != nil {
+		if err := app.LogSink.Close(shutdownCtx); err != nil {
+			slog.Warn("logs pendentes não gravados dentro do prazo", "error", err)
+		}
+	}
+
+	if app.DB != nil {
+		if err := app.DB.Client().Disconnect(shutdownCtx); err != nil {
+			slog.Error("erro ao desconectar do MongoDB", "error", err)
+		} else {
+			slog.Info("desconectado do MongoDB")
+		}
+	}
+
+	slog.Info("servidor encerrado")
+	return shutdownErr
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// ===========================================
+// REQUEST ID E W3C TRACE CONTEXT
+// ===========================================
+
+// TraceContext guarda os identificadores W3C (traceparent) da requisição
+type TraceContext struct {
+	TraceID  string // 32 hex, compartilhado por todo o trace
+	SpanID   string // 16 hex, gerado para esta requisição
+	ParentID string // 16 hex, span do chamador (vazio se iniciamos o trace)
+	Flags    string // 2 hex, ex.: "01" = sampled
+}
+
+// Traceparent formata o header traceparent com o span desta requisição
+func (t TraceContext) Traceparent() string {
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + t.Flags
+}
+
+// TraceFromContext retorna o trace da requisição, se houver
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	t, ok := ctx.Value(traceContextKey).(TraceContext)
+	return t, ok
+}
+
+var (
+	// traceparentPattern segue https://www.w3.org/TR/trace-context/#traceparent-header
+	traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+	// requestIDPattern limita o X-Request-ID recebido a caracteres seguros para logs
+	requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+)
+
+// parseTraceparent valida o header recebido; IDs zerados e a versão ff são inválidos
+func parseTraceparent(header string) (TraceContext, bool) {
+	m := traceparentPattern.FindStringSubmatch(strings.TrimSpace(header))
+	if m == nil || m[1] == "ff" {
+		return TraceContext{}, false
+	}
+	if m[2] == strings.Repeat("0", 32) || m[3] == strings.Repeat("0", 16) {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceID: m[2], ParentID: m[3], Flags: m[4]}, true
+}
+
+// RequestIDMiddleware aceita ou gera X-Request-ID e traceparent, guarda
+// ambos no contexto (incluídos em todos os logs com slog.*Context) e os
+// devolve nos headers da resposta.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		trace, ok := parseTraceparent(r.Header.Get("traceparent"))
+		if !ok {
+			trace = TraceContext{TraceID: randomHex(16), Flags: "01"}
+		}
+		trace.SpanID = randomHex(8)
+
+		w.Header().Set("X-Request-ID", requestID)
+		w.Header().Set("traceparent", trace.Traceparent())
+
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx = context.WithValue(ctx, traceContextKey, trace)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID gera um identificador aleatório de 16 bytes em hexadecimal
+func newRequestID() string {
+	return randomHex(16)
+}
+
+// randomHex gera n bytes aleatórios em hexadecimal
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// mongoCommandMonitor registra as falhas (e, em debug, os sucessos) dos
+// comandos enviados ao MongoDB e alimenta as métricas de duração/erros.
+// O driver repassa o contexto da operação, então cada registro carrega o
+// request_id e o trace_id da requisição.
+func mongoCommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			metrics.ObserveMongoCommand(e.CommandName, e.Duration, false)
+			slog.DebugContext(ctx, "comando MongoDB concluído",
+				"command", e.CommandName,
+				"database", e.DatabaseName,
+				"duration_ms", float64(e.Duration.Microseconds())/1000)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			metrics.ObserveMongoCommand(e.CommandName, e.Duration, true)
+			slog.ErrorContext(ctx, "comando MongoDB falhou",
+				"command", e.CommandName,
+				"database", e.DatabaseName,
+				"duration_ms", float64(e.Duration.Microseconds())/1000,
+				"error", e.Failure)
+		},
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ===========================================
+// VALIDAÇÃO DE PAYLOADS
+// ===========================================
+
+const (
+	maxBodyBytes  = 1 << 20 // 1 MB
+	maxNameLength = 100
+	minUserAge    = 0
+	maxUserAge    = 150 // mesmo limite do $jsonSchema em mongo-init-prod.js
+)
+
+// FieldError descreve um campo inválido do payload
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors agrupa todos os campos inválidos de uma requisição
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	parts := make([]string, len(v))
+	for i, fe := range v {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// add registra um erro para o campo
+func (v *ValidationErrors) add(field, format string, args ...interface{}) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// readOnlyUserFields são gerados pelo servidor e não podem vir do cliente
+var readOnlyUserFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// errBodyTooLarge indica que o corpo excedeu maxBodyBytes
+var errBodyTooLarge = errors.New("corpo da requisição muito grande")
+
+// decodeUserPayload lê o corpo JSON e valida os campos de User.
+// Com partial=true (PATCH) os campos ausentes são ignorados; caso contrário
+// name, email e age são obrigatórios.
+func decodeUserPayload(w http.ResponseWriter, r *http.Request, partial bool) (UserPatch, error) {
+	var patch UserPatch
+
+	raw, err := decodeJSONObject(w, r)
+	if err != nil {
+		return patch, err
+	}
+
+	// Ordena as chaves para que a lista de erros seja determinística
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs ValidationErrors
+	for _, key := range keys {
+		value := raw[key]
+		switch {
+		case readOnlyUserFields[key]:
+			errs.add(key, "campo somente leitura")
+		case (key == "name" || key == "email" || key == "age") && isJSONNull(value):
+			// null não equivale a campo ausente: no PATCH seria ignorado em silêncio
+			errs.add(key, "não pode ser nulo")
+		case key == "name":
+			if json.Unmarshal(value, &patch.Name) != nil {
+				errs.add(key, "deve ser um texto")
+			}
+		case key == "email":
+			if json.Unmarshal(value, &patch.Email) != nil {
+				errs.add(key, "deve ser um texto")
+			}
+		case key == "age":
+			if json.Unmarshal(value, &patch.Age) != nil {
+				errs.add(key, "deve ser um número inteiro")
+			}
+		case key == "roles":
+			errs.add(key, "use PUT /users/{id}/roles")
+		default:
+			errs.add(key, "campo desconhecido")
+		}
+	}
+
+	validateUserPatch(&patch, partial, &errs)
+
+	if len(errs) > 0 {
+		return patch, errs
+	}
+	return patch, nil
+}
+
+// validateUserPatch aplica as regras de negócio aos campos decodificados
+func validateUserPatch(patch *UserPatch, partial bool, errs *ValidationErrors) {
+	if patch.Name != nil {
+		name := strings.TrimSpace(*patch.Name)
+		patch.Name = &name
+		if name == "" {
+			errs.add("name", "não pode ser vazio")
+		} else if utf8.RuneCountInString(name) > maxNameLength {
+			errs.add("name", "deve ter no máximo %d caracteres", maxNameLength)
+		}
+	} else if !partial && !errs.has("name") {
+		errs.add("name", "campo obrigatório")
+	}
+
+	if patch.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*patch.Email))
+		patch.Email = &email
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			errs.add("email", "formato de email inválido")
+		}
+	} else if !partial && !errs.has("email") {
+		errs.add("email", "campo obrigatório")
+	}
+
+	if patch.Age != nil {
+		if *patch.Age < minUserAge || *patch.Age > maxUserAge {
+			errs.add("age", "deve estar entre %d e %d", minUserAge, maxUserAge)
+		}
+	} else if !partial && !errs.has("age") {
+		errs.add("age", "campo obrigatório")
+	}
+}
+
+// has indica se já existe erro registrado para o campo
+func (v ValidationErrors) has(field string) bool {
+	for _, fe := range v {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSONObject lê o corpo (limitado a maxBodyBytes) como um objeto JSON
+func decodeJSONObject(w http.ResponseWriter, r *http.Request) (map[string]json.RawMessage, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, errBodyTooLarge
+		}
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(&raw); err != nil || raw == nil {
+		return nil, errors.New("JSON inválido")
+	}
+	// Rejeita conteúdo extra depois do objeto, inclusive "}" ou "]" soltos
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return nil, errors.New("JSON inválido")
+	}
+	return raw, nil
+}
+
+// isJSONNull indica se o valor bruto é o literal null
+func isJSONNull(value json.RawMessage) bool {
+	return string(bytes.TrimSpace(value)) == "null"
+}
+
+// writePayloadError responde com o status adequado ao erro de decodificação.
+// Erros de validação viram 422 com a lista de campos inválidos.
+func writePayloadError(w http.ResponseWriter, r *http.Request, err error) {
+	var verrs ValidationErrors
+	switch {
+	case errors.As(err, &verrs):
+		writeErrorBody(w, r, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  "Dados inválidos",
+			"fields": verrs,
+		})
+	case errors.Is(err, errBodyTooLarge):
+		writeError(w, r, http.StatusRequestEntityTooLarge, "Corpo da requisição muito grande")
+	default:
+		writeError(w, r, http.StatusBadRequest, "JSON inválido")
+	}
+}
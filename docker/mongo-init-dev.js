@@ -4,9 +4,22 @@
 
 print('🚀 Inicializando MongoDB para DESENVOLVIMENTO...');
 
-// Criar usuário de desenvolvimento
 db = db.getSiblingDB('app_development');
 
+// readWrite não inclui collMod, usado pela aplicação para ajustar o prazo
+// do índice TTL de logs quando LOG_RETENTION muda
+db.createRole({
+  role: 'logRetention',
+  privileges: [
+    {
+      resource: { db: 'app_development', collection: 'logs' },
+      actions: ['collMod']
+    }
+  ],
+  roles: []
+});
+
+// Criar usuário de desenvolvimento
 db.createUser({
   user: 'dev_user',
   pwd: 'dev_password123',
@@ -14,6 +27,10 @@ db.createUser({
     {
       role: 'readWrite',
       db: 'app_development'
+    },
+    {
+      role: 'logRetention',
+      db: 'app_development'
     }
   ]
 });
@@ -4,9 +4,22 @@
 
 print('🚀 Inicializando MongoDB para HOMOLOGAÇÃO...');
 
-// Criar usuário de homologação
 db = db.getSiblingDB('app_homologation');
 
+// readWrite não inclui collMod, usado pela aplicação para ajustar o prazo
+// do índice TTL de logs quando LOG_RETENTION muda
+db.createRole({
+  role: 'logRetention',
+  privileges: [
+    {
+      resource: { db: 'app_homologation', collection: 'logs' },
+      actions: ['collMod']
+    }
+  ],
+  roles: []
+});
+
+// Criar usuário de homologação
 db.createUser({
   user: 'hml_user',
   pwd: 'hml_password456',
@@ -14,6 +27,10 @@ db.createUser({
     {
       role: 'readWrite',
       db: 'app_homologation'
+    },
+    {
+      role: 'logRetention',
+      db: 'app_homologation'
     }
   ]
 });
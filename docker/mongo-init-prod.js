@@ -4,9 +4,22 @@
 
 print('🚀 Inicializando MongoDB para PRODUÇÃO...');
 
-// Criar usuário de produção
 db = db.getSiblingDB('app_production');
 
+// readWrite não inclui collMod, usado pela aplicação para ajustar o prazo
+// do índice TTL de logs quando LOG_RETENTION muda
+db.createRole({
+  role: 'logRetention',
+  privileges: [
+    {
+      resource: { db: 'app_production', collection: 'logs' },
+      actions: ['collMod']
+    }
+  ],
+  roles: []
+});
+
+// Criar usuário de produção
 db.createUser({
   user: 'prod_user',
   pwd: 'prod_super_secure_password789',
@@ -14,6 +27,10 @@ db.createUser({
     {
       role: 'readWrite',
       db: 'app_production'
+    },
+    {
+      role: 'logRetention',
+      db: 'app_production'
     }
   ]
 });